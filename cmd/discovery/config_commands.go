@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	validateConfigCommand = "validate-config"
+	printConfigCommand    = "print-config"
+)
+
+// validateConfig checks the configuration set through flags, environment variables and the configuration file
+// without contacting Kubernetes, reporting every problem found. It returns the exit code.
+func validateConfig() int {
+	_, errs := config.Validate(integrationVersion)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			log.Printf("invalid configuration: %s", err)
+		}
+		return exitKubernetesConfigurationReadError
+	}
+
+	fmt.Println("configuration is valid")
+	return 0
+}
+
+// printConfig prints the effective value of every option and the source it was taken from. It returns the exit code.
+func printConfig() int {
+	settings, err := config.Settings()
+	if err != nil {
+		log.Printf("failed read the configuration: %s ", err)
+		return exitKubernetesConfigurationReadError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tSOURCE")
+	for _, s := range settings {
+		source := s.Source
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Value, source)
+	}
+	_ = w.Flush()
+
+	return 0
+}
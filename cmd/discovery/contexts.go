@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/discovery"
+	log "github.com/sirupsen/logrus"
+)
+
+// discoverContexts runs the discovery in each of the contexts configured one after another, returning the items
+// discovered in all of them with the cluster name of their context. Contexts failing are reported as part of a
+// PartialOutputError, unless every one of them fails or the discovery is strict.
+func discoverContexts(ctx context.Context, c *config.Config) (discovery.Output, error) {
+	var output discovery.Output
+	partialErr := &discovery.PartialOutputError{}
+	var firstErr *exitError
+	failed := 0
+
+	for _, cc := range c.Contexts {
+		contextConfig := *c
+		contextConfig.Contexts = nil
+		contextConfig.KubeContext = cc.Context
+		contextConfig.ClusterName = cc.ClusterName
+
+		log.Infof("discovering context %q as cluster %q", cc.Context, cc.ClusterName)
+		contextOutput, err := discoverCluster(ctx, &contextConfig)
+
+		var contextPartialErr *discovery.PartialOutputError
+		var exitErr *exitError
+		switch {
+		case errors.As(err, &contextPartialErr):
+			for _, sourceErr := range contextPartialErr.Errors {
+				partialErr.Errors = append(partialErr.Errors, &discovery.SourceError{
+					Source: fmt.Sprintf("%s of context %q", sourceErr.Source, cc.Context),
+					Err:    sourceErr.Err,
+				})
+			}
+		case errors.As(err, &exitErr):
+			contextErr := &exitError{exitErr.code, fmt.Errorf("context %q: %w", cc.Context, exitErr.err)}
+			if c.Strict {
+				return nil, contextErr
+			}
+			if firstErr == nil {
+				firstErr = contextErr
+			}
+			failed++
+			partialErr.Errors = append(partialErr.Errors, &discovery.SourceError{
+				Source: fmt.Sprintf("context %q", cc.Context),
+				Err:    exitErr.err,
+			})
+			continue
+		}
+
+		output = append(output, contextOutput...)
+	}
+
+	if failed == len(c.Contexts) {
+		return nil, firstErr
+	}
+	if len(partialErr.Errors) > 0 {
+		return output, partialErr
+	}
+	return output, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/http"
+	kubelet "github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+const diagnoseCommand = "diagnose"
+
+// diagnose makes every probe the connector tries to reach the kubelet and checks the permissions discovery needs,
+// printing the outcome of each along with hints to fix failures. It returns the exit code.
+func diagnose() int {
+	c, err := config.NewConfig(integrationVersion)
+	if err != nil {
+		log.Printf("failed read the configuration: %s ", err)
+		return exitKubernetesConfigurationReadError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	k8sConfig, err := getK8sConfig(c)
+	if err != nil {
+		log.Printf("failed setting kubernetes configuration: %s", err)
+		return exitKubernetesConfigurationBuildError
+	}
+
+	k8s, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		log.Printf("failed building kubernetes client: %s", err)
+		return exitKubernetesClientBuildError
+	}
+
+	// Only warnings of the connector are logged, since the outcome of every probe is printed.
+	logger := log.New()
+	logger.SetLevel(log.WarnLevel)
+
+	d := http.Diagnose(ctx, k8s, c, k8sConfig, logger)
+	printDiagnosis(os.Stdout, c, d)
+
+	checks := kubelet.CheckPermissions(ctx, k8s, requiredPermissions(c))
+	printPermissions(os.Stdout, checks)
+
+	if !d.Connected() {
+		return exitNoConnectionToKubelet
+	}
+	return 0
+}
+
+// requiredPermissions returns the permissions discovery may need: nodes/proxy to reach the kubelet through the
+// API server, nodes to find the kubelet port, and services in the namespaces discovered.
+func requiredPermissions(c *config.Config) []kubelet.Permission {
+	permissions := []kubelet.Permission{
+		{Verb: "get", Resource: "nodes", Subresource: "proxy"},
+		{Verb: "get", Resource: "nodes"},
+	}
+
+	namespaces := c.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	for _, ns := range namespaces {
+		permissions = append(permissions, kubelet.Permission{Verb: "list", Resource: "services", Namespace: ns})
+	}
+
+	return permissions
+}
+
+func printDiagnosis(out io.Writer, c *config.Config, d *http.Diagnosis) {
+	fmt.Fprintf(out, "Node: %s\n", c.NodeName)
+	fmt.Fprintf(out, "Service account token: %s (present: %t)\n", d.TokenFile, d.TokenPresent)
+
+	if d.PortErr != nil {
+		fmt.Fprintf(out, "Kubelet port lookup: failed, from %s: %v\n", http.PortSourceNodeEndpoint, d.PortErr)
+		fmt.Fprintf(out, "  hint: grant get on nodes to the service account, or set --%s\n", config.FlagPort)
+	}
+	fmt.Fprintf(out, "Kubelet port: %d, from %s\n", d.Port, d.PortSource)
+	fmt.Fprintf(out, "Kubelet scheme tried locally: %s\n\n", d.Scheme)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROBE\tURL\tSTATUS\tTLS\tLATENCY\tRESULT")
+	for _, p := range d.Probes {
+		status, result := "-", "ok"
+		if p.StatusCode != 0 {
+			status = fmt.Sprint(p.StatusCode)
+		}
+		if p.Err != nil {
+			result = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, p.URL, status, p.TLSVerification, p.Latency.Round(time.Millisecond), result)
+	}
+	_ = w.Flush()
+
+	for _, p := range d.Probes {
+		if p.Err == nil {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s: %v\n  hint: %s\n", p.Name, p.Err, p.Hint)
+	}
+	fmt.Fprintln(out)
+}
+
+func printPermissions(out io.Writer, checks []kubelet.PermissionCheck) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERMISSION\tALLOWED\tREASON")
+	for _, check := range checks {
+		allowed, reason := fmt.Sprint(check.Allowed), check.Reason
+		if check.Err != nil {
+			allowed, reason = "unknown", check.Err.Error()
+		}
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Permission, allowed, reason)
+	}
+	_ = w.Flush()
+}
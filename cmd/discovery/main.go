@@ -1,19 +1,26 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
 	"github.com/newrelic/nri-discovery-kubernetes/internal/discovery"
 	"github.com/newrelic/nri-discovery-kubernetes/internal/http"
 	kubelet "github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/homedir"
 )
 
@@ -27,37 +34,131 @@ const (
 	exitKubernetesConfigurationBuildError
 	exitKubernetesClientBuildError
 	exitKubeletClientBuildError
+	exitCustomResourceDiscovererBuildError
+	exitChangesStateReadError
+	exitRenderTemplateError
+	exitRenderInputError
+	exitUnresolvedVariables
+	exitRulesBuildError
+	exitCustomizationsBuildError
+	exitOutputWriteError
 )
 
+// commands run instead of the discovery when given as first argument, returning the exit code.
+var commands = map[string]func() int{
+	renderCommand:         render,
+	validateConfigCommand: validateConfig,
+	printConfigCommand:    printConfig,
+	diagnoseCommand:       diagnose,
+}
+
+// exitError is a failure of the discovery run along with the exit code reported for it.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, found := commands[os.Args[1]]; found {
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+			os.Exit(command())
+		}
+	}
+
 	config, err := config.NewConfig(integrationVersion)
 	if err != nil {
 		log.Printf("failed read the configuration: %s ", err)
 		os.Exit(exitKubernetesConfigurationReadError)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	r, err := newRunner(config)
+	if err != nil {
+		log.Printf("failed to read the previous output: %s", err)
+		os.Exit(exitChangesStateReadError)
+	}
+
+	if config.WatchInterval > 0 {
+		r.start(ctx)
+		r.watch(ctx)
+		r.close()
+		return
+	}
+
+	// Payloads are posted within the deadline of the run, so retries do not delay exiting past it.
+	runCtx, cancel := runContext(ctx, config)
+	r.start(runCtx)
+	code := r.run(runCtx)
+	r.close()
+	cancel()
+	if code != 0 {
+		stop()
+		os.Exit(code)
+	}
+}
+
+// discover runs the discovery in the cluster configured, or in each of the contexts if several are.
+func discover(ctx context.Context, config *config.Config) (discovery.Output, error) {
+	if len(config.Contexts) > 0 {
+		return discoverContexts(ctx, config)
+	}
+	return discoverCluster(ctx, config)
+}
+
+// discoverCluster builds the clients configured and runs the discovery. Failures are returned as an exitError,
+// unless only part of the discovery failed.
+func discoverCluster(ctx context.Context, config *config.Config) (discovery.Output, error) {
 	k8sConfig, err := getK8sConfig(config)
 	if err != nil {
-		log.Printf("setting kubernetes configuration: %s", err)
-		os.Exit(exitKubernetesConfigurationBuildError)
+		return nil, &exitError{exitKubernetesConfigurationBuildError, fmt.Errorf("setting kubernetes configuration: %w", err)}
 	}
 
 	k8s, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
-		log.Printf("building kubernetes client: %s", err)
-		os.Exit(exitKubernetesClientBuildError)
+		return nil, &exitError{exitKubernetesClientBuildError, fmt.Errorf("building kubernetes client: %w", err)}
 	}
 
-	connector := http.DefaultConnector(k8s, config, k8sConfig, log.New())
+	if config.RBACPreflight {
+		permitted, degraded, err := kubelet.Preflight(ctx, k8s, config, log.StandardLogger())
+		if err != nil {
+			return forbidden(config, err)
+		}
+		if len(degraded) > 0 {
+			log.Warnf("discovery is degraded by missing RBAC permissions of the service account: %s", strings.Join(degraded, "; "))
+		}
+		config = permitted
+	}
 
-	httpClient, err := http.NewClient(connector, http.WithMaxRetries(config.Retries))
+	kube, err := getKubelet(ctx, config, k8s, k8sConfig)
 	if err != nil {
-		log.Printf("building kubelet client: %s", err)
-		os.Exit(exitKubeletClientBuildError)
+		return nil, &exitError{exitKubeletClientBuildError, fmt.Errorf("building kubelet client: %w", err)}
 	}
 
-	kube := kubelet.New(httpClient, config)
 	discoverer := discovery.NewDiscoverer(config.Namespaces, kube, config.DiscoverServices)
+	discoverer.SetStrict(config.Strict)
+
+	rules, err := discovery.NewRules(config)
+	if err != nil {
+		return nil, &exitError{exitRulesBuildError, fmt.Errorf("building rules: %w", err)}
+	}
+	discoverer.SetRules(rules)
+
+	customizations, err := discovery.NewCustomizations(config)
+	if err != nil {
+		return nil, &exitError{exitCustomizationsBuildError, fmt.Errorf("building customizations: %w", err)}
+	}
+	discoverer.SetCustomizations(customizations)
 
 	// If discovering services, initialize and set the service discoverer
 	if config.DiscoverServices {
@@ -65,38 +166,117 @@ func main() {
 		discoverer.SetServiceDiscoverer(serviceDiscoverer)
 	}
 
-	output, err := discoverer.Run()
-	if err != nil {
-		log.Printf("failed to connect to Kubernetes: %s", err)
-		os.Exit(exitNoConnectionToKubelet)
+	// If discovering custom resources, initialize and set the custom resource discoverer
+	if config.DiscoverCustomResources {
+		dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+		if err != nil {
+			return nil, &exitError{exitKubernetesClientBuildError, fmt.Errorf("building kubernetes dynamic client: %w", err)}
+		}
+
+		customResourceDiscoverer, err := kubelet.NewCustomResourceDiscoverer(dynamicClient, config)
+		if err != nil {
+			return nil, &exitError{exitCustomResourceDiscovererBuildError, fmt.Errorf("building custom resource discoverer: %w", err)}
+		}
+		discoverer.SetCustomResourceDiscoverer(customResourceDiscoverer)
+	}
+
+	// If discovering nodes, initialize and set the node discoverer
+	if config.DiscoverNodes {
+		discoverer.SetNodeDiscoverer(kubelet.NewNodeDiscoverer(k8s, config))
+	}
+
+	output, err := discoverer.Run(ctx)
+	if err != nil && !errors.As(err, new(*discovery.PartialOutputError)) {
+		return nil, &exitError{exitNoConnectionToKubelet, fmt.Errorf("failed to connect to Kubernetes: %w", err)}
+	}
+
+	return output, err
+}
+
+// forbidden returns the result of a discovery whose items cannot be discovered at all with the permissions of the
+// service account: an empty output along with the error of its source, or the error alone if the discovery is strict.
+func forbidden(config *config.Config, err error) (discovery.Output, error) {
+	if config.Strict {
+		return nil, &exitError{exitNoConnectionToKubelet, fmt.Errorf("failed to connect to Kubernetes: %w", err)}
 	}
 
-	bytes, err := json.Marshal(output)
+	source := discovery.SourcePods
+	switch {
+	case config.DiscoverServices:
+		source = discovery.SourceServices
+	case config.DiscoverNodes:
+		source = discovery.SourceNodes
+	}
+	return discovery.Output{}, &discovery.PartialOutputError{Errors: []*discovery.SourceError{{Source: source, Err: err}}}
+}
+
+// runContext returns the context of a discovery run, which is cancelled along with the parent one, e.g. on SIGTERM,
+// and when the deadline is exceeded if one is configured.
+func runContext(parent context.Context, c *config.Config) (context.Context, context.CancelFunc) {
+	if c.Deadline <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, time.Duration(c.Deadline)*time.Millisecond)
+}
+
+// getKubelet returns the Kubelet implementation listing pods from the configured source.
+// When allowed, pods are listed from the API server if the kubelet cannot be reached by any means.
+func getKubelet(ctx context.Context, c *config.Config, k8s kubernetes.Interface, k8sConfig *rest.Config) (kubelet.Kubelet, error) {
+	if c.PodsSource == config.PodsSourceAPIServer {
+		log.Infof("listing pods from the API server")
+		return kubelet.NewAPIServerKubelet(k8s, c), nil
+	}
+
+	connector := http.DefaultConnector(k8s, c, k8sConfig, log.New())
+
+	httpClient, err := http.NewClient(ctx, connector, http.WithMaxRetries(c.Retries), http.WithBackoff(c.Backoff, c.MaxBackoff))
 	if err != nil {
-		log.Printf("failed to marshal result to Json: %s", err)
-		os.Exit(exitJSONMarchallError)
+		if !c.APIServerFallback {
+			return nil, err
+		}
+
+		log.Warnf("kubelet not reachable, listing pods from the API server instead: %v", err)
+		return kubelet.NewAPIServerKubelet(k8s, c), nil
 	}
-	fmt.Println(string(bytes))
+
+	return kubelet.New(httpClient, c), nil
 }
 
 func getK8sConfig(c *config.Config) (*rest.Config, error) {
-	inclusterConfig, err := rest.InClusterConfig()
-	if err == nil {
-		return inclusterConfig, nil
+	// The in-cluster config has no contexts, so a kubeconfig is expected when one is set.
+	if c.KubeContext == "" {
+		inclusterConfig, err := rest.InClusterConfig()
+		if err == nil {
+			if c.KubeAPIServer != "" {
+				inclusterConfig.Host = c.KubeAPIServer
+			}
+			return inclusterConfig, nil
+		}
+		log.Warnf("collecting in cluster config: %v", err)
 	}
-	log.Warnf("collecting in cluster config: %v", err)
 
 	kubeconf := c.KubeConfigFile
 	if kubeconf == "" {
 		kubeconf = path.Join(homedir.HomeDir(), ".kube", "config")
 	}
 
-	inclusterConfig, err = clientcmd.BuildConfigFromFlags("", kubeconf)
+	inclusterConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconf},
+		&clientcmd.ConfigOverrides{
+			CurrentContext: c.KubeContext,
+			ClusterInfo:    clientcmdapi.Cluster{Server: c.KubeAPIServer},
+		},
+	).ClientConfig()
 	if err != nil {
 		return nil, fmt.Errorf("could not load local kube config: %w", err)
 	}
 
-	log.Warnf("using local kube config: %q", kubeconf)
+	if c.KubeContext != "" {
+		log.Infof("using context %q of local kube config: %q", c.KubeContext, kubeconf)
+	} else {
+		log.Warnf("using local kube config: %q", kubeconf)
+	}
 
 	return inclusterConfig, nil
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// configMapDataLink is the symlink swapped when a file mounted from a ConfigMap is updated.
+const configMapDataLink = "..data"
+
+// watchConfigFile notifies through the returned channel when the file changes, until the context is done.
+// The directory is watched instead of the file, since editors and ConfigMap updates replace files instead of writing them.
+func watchConfigFile(ctx context.Context, file string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating watcher: %w", err)
+	}
+
+	file = filepath.Clean(file)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close() // nolint: errcheck
+		return nil, fmt.Errorf("watching %q: %w", filepath.Dir(file), err)
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close() // nolint: errcheck
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if name != file && filepath.Base(name) != configMapDataLink {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+					continue
+				}
+				// Changes are coalesced while the previous one is not handled yet.
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("watching configuration file: %v", err)
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/discovery"
+	log "github.com/sirupsen/logrus"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	renderCommand = "render"
+
+	flagRenderTemplate = "template"
+	flagRenderInput    = "input"
+)
+
+// render prints the infra agent integrations config template expanded for each item discovered, as the agent would
+// run it, reporting the items not matching it and the placeholders left unresolved. It returns the exit code.
+func render() int {
+	templateFile := flag.String(flagRenderTemplate, "", "Infra agent integrations config with ${discovery.*} placeholders to render")
+	inputFile := flag.String(flagRenderInput, "", "(optional) File with a JSON output to render the template with. Items are discovered if not set, which requires the discovery configuration")
+	flag.Parse()
+
+	if *templateFile == "" {
+		log.Printf("the template to render must be set with --%s", flagRenderTemplate)
+		return exitRenderTemplateError
+	}
+
+	data, err := os.ReadFile(*templateFile)
+	if err != nil {
+		log.Printf("failed to read the template: %s", err)
+		return exitRenderTemplateError
+	}
+
+	template, err := discovery.ParseTemplate(data)
+	if err != nil {
+		log.Printf("failed to parse the template: %s", err)
+		return exitRenderTemplateError
+	}
+
+	output, err := renderInput(*inputFile)
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		log.Print(exitErr)
+		return exitErr.code
+	}
+
+	code := 0
+	for _, item := range template.Render(output) {
+		switch {
+		case item.Mismatch != "":
+			log.Infof("item %s does not match variable %q", item.DiscoveryID, item.Mismatch)
+			continue
+		case len(item.Unresolved) > 0:
+			log.Warnf("item %s has unresolved variables %v, the agent does not run its integrations", item.DiscoveryID, item.Unresolved)
+			code = exitUnresolvedVariables
+		}
+
+		fmt.Printf("# discoveryID: %s\n%s---\n", item.DiscoveryID, item.Config)
+	}
+
+	return code
+}
+
+// renderInput returns the output read from the input file, or discovered if none is set. The configuration is only
+// read to discover, so rendering an input does not require the cluster settings.
+func renderInput(inputFile string) (discovery.Output, error) {
+	if inputFile == "" {
+		c, err := config.NewConfig(integrationVersion)
+		if err != nil {
+			return nil, &exitError{exitKubernetesConfigurationReadError, fmt.Errorf("failed read the configuration: %w", err)}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		ctx, cancel := runContext(ctx, c)
+		defer cancel()
+
+		output, err := discover(ctx, c)
+		var partialErr *discovery.PartialOutputError
+		if errors.As(err, &partialErr) {
+			for _, sourceErr := range partialErr.Errors {
+				log.Warnf("partial discovery result, %s", sourceErr)
+			}
+			return output, nil
+		}
+		return output, err
+	}
+
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, &exitError{exitRenderInputError, fmt.Errorf("reading input: %w", err)}
+	}
+
+	var output discovery.Output
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, &exitError{exitRenderInputError, fmt.Errorf("parsing input: %w", err)}
+	}
+
+	return output, nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/discovery"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/http"
+	log "github.com/sirupsen/logrus"
+)
+
+// webhookReloadTimeout bounds the wait for the payloads queued to the webhook when the configuration is reloaded.
+const webhookReloadTimeout = 5 * time.Second
+
+// runner discovers and prints the output, keeping across runs what is needed to serve stale
+// output and to print the changes from the previous one.
+type runner struct {
+	config      *config.Config
+	outputCache *discovery.OutputCache
+	renderer    *discovery.Renderer
+	previous    discovery.Output
+	webhook     *http.Webhook
+	// pushed is the last output posted to the webhook, to post only its changes if configured.
+	pushed discovery.Output
+}
+
+func newRunner(c *config.Config) (*runner, error) {
+	r := &runner{
+		config:      c,
+		outputCache: discovery.NewOutputCache(c),
+		renderer:    discovery.NewRenderer(c),
+		previous:    discovery.Output{},
+		webhook:     newWebhook(c),
+		pushed:      discovery.Output{},
+	}
+
+	if c.ChangesStateFile != "" {
+		previous, err := discovery.LoadState(c.ChangesStateFile)
+		if err != nil {
+			return nil, err
+		}
+		r.previous = previous
+	}
+
+	return r, nil
+}
+
+// newWebhook creates the webhook configured, merging the changes queued when its queue is full
+// so none is lost if only changes are posted.
+func newWebhook(c *config.Config) *http.Webhook {
+	webhook := http.NewWebhook(c, log.StandardLogger())
+	if webhook != nil && c.WebhookPayload == config.WebhookPayloadChanges {
+		webhook.SetMerge(mergeChanges)
+	}
+	return webhook
+}
+
+// mergeChanges merges the changes of an older webhook payload into the ones of a newer one.
+func mergeChanges(older, newer []byte) ([]byte, error) {
+	var olderChanges, newerChanges discovery.ChangeSet
+	if err := json.Unmarshal(older, &olderChanges); err != nil {
+		return nil, fmt.Errorf("parsing older changes: %w", err)
+	}
+	if err := json.Unmarshal(newer, &newerChanges); err != nil {
+		return nil, fmt.Errorf("parsing newer changes: %w", err)
+	}
+	return json.Marshal(discovery.MergeChanges(olderChanges, newerChanges))
+}
+
+// start starts posting to the webhook, if configured, until the runner is closed or the context is cancelled.
+func (r *runner) start(ctx context.Context) {
+	if r.webhook != nil {
+		r.webhook.Start(ctx)
+	}
+}
+
+// close waits until the payloads queued are posted to the webhook, if configured.
+func (r *runner) close() {
+	if r.webhook != nil {
+		r.webhook.Close()
+	}
+}
+
+// watch discovers and prints the output every interval until the context is cancelled, e.g. on SIGTERM.
+// The configuration is reloaded when the configuration file changes, discovering again right away.
+func (r *runner) watch(ctx context.Context) {
+	var reloads <-chan struct{}
+	if r.config.ConfigFile != "" {
+		var err error
+		if reloads, err = watchConfigFile(ctx, r.config.ConfigFile); err != nil {
+			log.Warnf("configuration file changes are not reloaded: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(r.config.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		if code := r.run(ctx); code != 0 {
+			log.Warnf("discovery failed with exit code %d, retrying in %s", code, r.config.WatchInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-reloads:
+			r.reload(ctx)
+			ticker.Reset(r.config.WatchInterval)
+		}
+	}
+}
+
+// reload reads the configuration again, keeping the current one if the new one is not valid.
+// The previous output is kept, so changes are still printed from it.
+func (r *runner) reload(ctx context.Context) {
+	c, err := config.NewConfig(integrationVersion)
+	if err == nil && c.WatchInterval <= 0 {
+		err = errors.New("the watch interval cannot be disabled while watching")
+	}
+	if err != nil {
+		log.Warnf("configuration not reloaded, keeping the current one: %v", err)
+		return
+	}
+
+	// Payloads queued are posted with the webhook configured when they were pushed, as long as it does not delay
+	// watching for long.
+	if r.webhook != nil {
+		r.webhook.CloseWithin(webhookReloadTimeout)
+	}
+	// A new webhook target has not received any output, so every item is posted to it as added.
+	if c.WebhookURL != r.config.WebhookURL || c.WebhookPayload != r.config.WebhookPayload {
+		r.pushed = discovery.Output{}
+	}
+
+	r.config = c
+	r.outputCache = discovery.NewOutputCache(c)
+	r.renderer = discovery.NewRenderer(c)
+	r.webhook = newWebhook(c)
+	r.start(ctx)
+
+	log.Infof("configuration reloaded from %q", c.ConfigFile)
+}
+
+// run discovers once, within the deadline configured, and prints the result. It returns the exit code of the run.
+func (r *runner) run(parent context.Context) int {
+	ctx, cancel := runContext(parent, r.config)
+	defer cancel()
+
+	output, err := discover(ctx, r.config)
+	var partialErr *discovery.PartialOutputError
+	var exitErr *exitError
+	switch {
+	case errors.As(err, &exitErr):
+		log.Print(exitErr)
+		output = r.lastKnownOutput()
+		if output == nil {
+			return exitErr.code
+		}
+		return r.print(output, true)
+	case errors.As(err, &partialErr):
+		// The items discovered are still printed, failures are only reported in stderr.
+		for _, sourceErr := range partialErr.Errors {
+			log.Warnf("partial discovery result, %s", sourceErr)
+		}
+	}
+
+	// Partial outputs are cached too, as they are the last items known to be discovered.
+	if r.outputCache != nil {
+		if err := r.outputCache.Store(output); err != nil {
+			log.Warnf("caching output: %v", err)
+		}
+	}
+
+	return r.print(output, false)
+}
+
+// lastKnownOutput returns the output cached by a previous run, flagged as stale, or nil if there is none to serve.
+func (r *runner) lastKnownOutput() discovery.Output {
+	if r.outputCache == nil {
+		return nil
+	}
+
+	output, storedAt, err := r.outputCache.Load()
+	if err != nil {
+		log.Warnf("cached output not served: %v", err)
+		return nil
+	}
+
+	log.Warnf("serving the output cached at %s as stale", storedAt.Format(time.RFC3339))
+	return output
+}
+
+// print writes the output, or its changes from the previous one, in the format configured to stdout, and each item
+// to the output directory if configured. Stale outputs served from the output cache are only printed, so the
+// changes state, output directory and webhook keep the last output discovered.
+// Failing to write the output directory or file is reported with its own exit code once the output is printed.
+func (r *runner) print(output discovery.Output, stale bool) int {
+	code := 0
+	if !stale {
+		if r.config.ChangesStateFile != "" {
+			if err := discovery.StoreState(r.config.ChangesStateFile, output); err != nil {
+				log.Warnf("storing output to compare with the next one: %v", err)
+			}
+		}
+
+		if r.config.OutputDir != "" {
+			if err := discovery.WriteOutputDir(r.config.OutputDir, output); err != nil {
+				log.Print(err)
+				code = exitOutputWriteError
+			}
+		}
+
+		r.push(output)
+	}
+
+	if printed := r.printOutput(output, stale); printed != 0 {
+		return printed
+	}
+	return code
+}
+
+// printOutput writes the output, or its changes from the previous one, in the format configured.
+func (r *runner) printOutput(output discovery.Output, stale bool) int {
+	if r.config.Changes == config.ChangesNone {
+		return r.write(r.renderer.Render(output))
+	}
+
+	changes := discovery.Diff(r.previous, output)
+	if !stale {
+		r.previous = output
+	}
+
+	if r.config.Changes == config.ChangesAlongside {
+		return r.write(r.renderer.Marshal(discovery.OutputWithChanges{Output: output, Changes: changes}))
+	}
+
+	// Unchanged outputs are not printed while watching, as there is nothing to report.
+	if r.config.WatchInterval > 0 && changes.Empty() {
+		return 0
+	}
+
+	return r.write(r.renderer.Marshal(changes))
+}
+
+// write prints the rendered result followed by a new line, also writing it to the output file if configured.
+func (r *runner) write(bytes []byte, err error) int {
+	if err != nil {
+		log.Printf("failed to render result: %s", err)
+		return exitJSONMarchallError
+	}
+	fmt.Println(string(bytes))
+
+	if r.config.OutputFile != "" {
+		if err := discovery.WriteOutputFile(r.config.OutputFile, append(bytes, '\n')); err != nil {
+			log.Print(err)
+			return exitOutputWriteError
+		}
+	}
+
+	return 0
+}
+
+// push queues the output, or its changes from the one pushed before, to be posted to the webhook if configured.
+// Unchanged outputs are not posted when only changes are.
+func (r *runner) push(output discovery.Output) {
+	if r.webhook == nil {
+		return
+	}
+
+	var payload interface{} = output
+	if r.config.WebhookPayload == config.WebhookPayloadChanges {
+		changes := discovery.Diff(r.pushed, output)
+		r.pushed = output
+		if changes.Empty() {
+			return
+		}
+		payload = changes
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Warnf("marshaling webhook payload: %v", err)
+		return
+	}
+	r.webhook.Push(data)
+}
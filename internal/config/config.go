@@ -2,11 +2,16 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -15,17 +20,89 @@ const (
 	DefaultTimeout = 5000        // Default timeout of 5 seconds in miliseconds
 	DefaultRetries = 5           // Default retries to 5
 
-	FlagHost              = "host"
-	FlagNamespaces        = "namespaces"
-	FlagPort              = "port"
-	FlagInsecure          = "insecure"
-	FlagTimeout           = "timeout"
-	FlagRetries           = "retries"
-	FlagTLS               = "tls"
-	FlagKubeConfigFile    = "kubeconfig"
-	FlagClusterName       = "cluster_name"
-	FlagNodeName          = "node_name"
-	FlagDiscoverServices  = "discover-services"
+	DefaultConnectionCacheTTL = 10 * time.Minute // Default time a cached kubelet connection is reused
+	DefaultMaxBackoff         = 30 * time.Second // Default maximum wait between retries
+	DefaultOutputCacheMaxAge  = 10 * time.Minute // Default maximum age of the cached output served when discovery fails
+	DefaultServicesPageSize   = 500              // Default number of services listed per request
+	DefaultServicesWorkers    = 10               // Default number of namespaces whose services are listed concurrently
+	DefaultWebhookQueueSize   = 10               // Default number of payloads waiting to be posted to the webhook
+
+	PodsSourceKubelet   = "kubelet"    // PodsSourceKubelet lists pods from the kubelet /pods endpoint.
+	PodsSourceAPIServer = "api-server" // PodsSourceAPIServer lists pods from the API server.
+
+	ChangesNone      = "none"      // ChangesNone prints the output only.
+	ChangesOnly      = "only"      // ChangesOnly prints the changes from the previous output instead of the output.
+	ChangesAlongside = "alongside" // ChangesAlongside prints both the output and the changes from the previous one.
+
+	WebhookPayloadOutput  = "output"  // WebhookPayloadOutput posts the whole output on every run.
+	WebhookPayloadChanges = "changes" // WebhookPayloadChanges posts the items added, removed and modified since the last payload, if any.
+
+	OutputFormatJSON             = "json"               // OutputFormatJSON prints the items as a JSON array, as the infra agent expects.
+	OutputFormatJSONPretty       = "json-pretty"        // OutputFormatJSONPretty prints the items as an indented JSON array.
+	OutputFormatNDJSON           = "ndjson"             // OutputFormatNDJSON prints each item as JSON in its own line.
+	OutputFormatYAML             = "yaml"               // OutputFormatYAML prints the items as a YAML list.
+	OutputFormatTable            = "table"              // OutputFormatTable prints the namespace, pod, container, address and ports of each item as a table.
+	OutputFormatPrometheusFileSD = "prometheus-file-sd" // OutputFormatPrometheusFileSD prints Prometheus file based service discovery target groups.
+	OutputFormatOTelK8sObserver  = "otel-k8s-observer"  // OutputFormatOTelK8sObserver prints pods and ports as OpenTelemetry k8s_observer endpoints.
+
+	FlagHost                    = "host"
+	FlagConfig                  = "config"
+	FlagNamespaces              = "namespaces"
+	FlagPort                    = "port"
+	FlagInsecure                = "insecure"
+	FlagTimeout                 = "timeout"
+	FlagDeadline                = "deadline"
+	FlagRetries                 = "retries"
+	FlagStrict                  = "strict"
+	FlagBackoff                 = "backoff"
+	FlagMaxBackoff              = "max-backoff"
+	FlagTLS                     = "tls"
+	FlagKubeConfigFile          = "kubeconfig"
+	FlagKubeContext             = "context"
+	FlagKubeAPIServer           = "kube-api-server"
+	FlagContexts                = "contexts"
+	FlagClusterName             = "cluster_name"
+	FlagNodeName                = "node_name"
+	FlagDiscoverServices        = "discover-services"
+	FlagServicesPageSize        = "services-page-size"
+	FlagServicesWorkers         = "services-workers"
+	FlagDiscoverCustomResources = "discover-custom-resources"
+	FlagCustomResourcesFile     = "custom-resources-file"
+	FlagDiscoverNodes           = "discover-nodes"
+	FlagPodsSource              = "pods-source"
+	FlagAPIServerFallback       = "api-server-fallback"
+	FlagRBACPreflight           = "rbac-preflight"
+
+	FlagKubeletScheme             = "kubelet-scheme"
+	FlagKubeletCAFile             = "kubelet-ca-file"
+	FlagKubeletClientCert         = "kubelet-client-cert"
+	FlagKubeletClientKey          = "kubelet-client-key"
+	FlagKubeletServerName         = "kubelet-server-name"
+	FlagKubeletInsecureSkipVerify = "kubelet-insecure-skip-verify"
+
+	FlagConnectionCacheFile = "connection-cache-file"
+	FlagConnectionCacheTTL  = "connection-cache-ttl"
+
+	FlagOutputCacheFile   = "output-cache-file"
+	FlagOutputCacheMaxAge = "output-cache-max-age"
+
+	FlagChanges          = "changes"
+	FlagChangesStateFile = "changes-state-file"
+	FlagWatchInterval    = "watch-interval"
+
+	FlagOutputFormat       = "output-format"
+	FlagPrometheusPortName = "prometheus-port-name"
+	FlagOutputFile         = "output-file"
+	FlagOutputDir          = "output-dir"
+
+	FlagWebhookURL             = "webhook-url"
+	FlagWebhookBearerTokenFile = "webhook-bearer-token-file"
+	FlagWebhookHMACSecretFile  = "webhook-hmac-secret-file"
+	FlagWebhookPayload         = "webhook-payload"
+	FlagWebhookQueueSize       = "webhook-queue-size"
+
+	FlagRulesFile = "rules-file"
+	FlagRulesOnly = "rules-only"
 
 	envPrefix            = "NRIA"
 	nodeNameEnvVar       = "NRI_KUBERNETES_NODE_NAME"
@@ -33,14 +110,29 @@ const (
 	clusterNameEnvVar    = "CLUSTER_NAME"
 )
 
+// outputFormats lists the formats supported by FlagOutputFormat.
+var outputFormats = []string{
+	OutputFormatJSON, OutputFormatJSONPretty, OutputFormatNDJSON, OutputFormatYAML, OutputFormatTable,
+	OutputFormatPrometheusFileSD, OutputFormatOTelK8sObserver,
+}
+
 var (
+	_ = flag.String(FlagConfig, "", "(optional) YAML file setting any option by its flag name, plus selectors, annotations, entityRewrites, customResources and rules. "+
+		"Flags and environment variables take precedence. It is reloaded on changes when watching")
 	_ = flag.String(FlagNamespaces, "", "(optional, default '') Comma separated list of namespaces to discover pods on")
 	_ = flag.Bool(FlagInsecure, false, `(optional, default false, deprecated) Use insecure (non-ssl) connection.
 For backwards compatibility this flag takes precedence over 'tls')`)
 
 	_ = flag.Int(FlagTimeout, DefaultTimeout, "(optional, default 5000) timeout in ms")
+	_ = flag.Int(FlagDeadline, 0, "(optional, default 0) deadline in ms for the whole discovery run, 0 means no deadline")
 	_ = flag.Int(FlagRetries, DefaultRetries, "(optional, default 5) number of retries before giving up the request to kubelet/API Server")
 
+	_ = flag.Bool(FlagStrict, false, "(optional, default false) Fail when any part of the discovery fails, instead of printing the items discovered and reporting the failures in stderr")
+
+	_ = flag.String(FlagBackoff, retry.BackoffLinear, "(optional, default "+retry.BackoffLinear+") backoff strategy between retries, one of '"+
+		retry.BackoffLinear+"', '"+retry.BackoffExponential+"' or '"+retry.BackoffExponentialJitter+"'")
+	_ = flag.Duration(FlagMaxBackoff, DefaultMaxBackoff, "(optional, default 30s) maximum wait between retries, including the one requested by servers through Retry-After")
+
 	_ = flag.Bool(FlagTLS, false, "(optional, default false) Use secure (tls) connection")
 	_ = flag.Int(FlagPort, DefaultPort, "(optional, default 10255) Port used to connect to the kubelet")
 	_ = flag.String(FlagHost, DefaultHost, "(optional, default "+DefaultHost+") Host used to connect to the kubelet")
@@ -49,9 +141,67 @@ For backwards compatibility this flag takes precedence over 'tls')`)
 	_ = flag.String(FlagNodeName, "", "(optional) Set node name to try to find its IP")
 
 	_ = flag.String(FlagKubeConfigFile, "", "(optional) Kubeconfig to use to connecto to kubelet")
+	_ = flag.String(FlagKubeContext, "", "(optional) Kubeconfig context to use instead of the current one. The in-cluster config is not used when set")
+	_ = flag.String(FlagKubeAPIServer, "", "(optional) URL of the API server, overriding the one of the in-cluster config or kubeconfig")
+	_ = flag.String(FlagContexts, "", "(optional) Comma separated list of kubeconfig contexts to discover one after another, "+
+		"each as context=cluster_name to set the clusterName of its items, which is the context name if not set")
 	_ = flag.Bool(FlagDiscoverServices, false, "(optional, default false) Discover Kubernetes services instead of just pods")
+	_ = flag.Int(FlagServicesPageSize, DefaultServicesPageSize, "(optional, default 500) number of services listed per request to the API server, 0 lists them all at once")
+	_ = flag.Int(FlagServicesWorkers, DefaultServicesWorkers, "(optional, default 10) number of namespaces whose services are listed concurrently")
+	_ = flag.Bool(FlagDiscoverCustomResources, false, "(optional, default false) Discover the custom resources declared in the custom resources file instead of pods")
+	_ = flag.String(FlagCustomResourcesFile, "", "(optional) YAML file declaring the custom resources to discover and the variables extracted from them")
+	_ = flag.String(FlagPodsSource, PodsSourceKubelet, "(optional, default "+PodsSourceKubelet+") Where pods are listed from, either '"+PodsSourceKubelet+"' or '"+PodsSourceAPIServer+"'")
+	_ = flag.Bool(FlagAPIServerFallback, true, "(optional, default true) List pods from the API server when the kubelet is not reachable")
+	_ = flag.String(FlagKubeletScheme, "", "(optional) Scheme used to connect to the kubelet, either 'http' or 'https'. Inferred from the tls flag and the port if not set")
+	_ = flag.String(FlagKubeletCAFile, "", "(optional) CA certificate used to verify the kubelet, instead of the cluster CA")
+	_ = flag.String(FlagKubeletClientCert, "", "(optional) Client certificate used to authenticate against the kubelet, requires kubelet-client-key")
+	_ = flag.String(FlagKubeletClientKey, "", "(optional) Client key used to authenticate against the kubelet, requires kubelet-client-cert")
+	_ = flag.String(FlagKubeletServerName, "", "(optional) Server name used to verify the kubelet certificate, instead of the host")
+	_ = flag.Bool(FlagKubeletInsecureSkipVerify, false, "(optional, default false) Skip the verification of the kubelet certificate")
+	_ = flag.String(FlagConnectionCacheFile, "", "(optional) File where the kubelet connection found is stored, so the following runs use it until it fails")
+	_ = flag.Duration(FlagConnectionCacheTTL, DefaultConnectionCacheTTL, "(optional, default 10m) Time since the kubelet connection stored in the connection cache file was last used after which it is probed again")
+	_ = flag.String(FlagOutputCacheFile, "", "(optional) File where the last output discovered, even partially, is stored, so it is printed flagged as stale when discovery fails")
+	_ = flag.Duration(FlagOutputCacheMaxAge, DefaultOutputCacheMaxAge, "(optional, default 10m) Maximum age of the output stored in the output cache file to be printed when discovery fails")
+	_ = flag.String(FlagChanges, ChangesNone, "(optional, default "+ChangesNone+") Print the items added, removed and modified since the previous output, either '"+
+		ChangesOnly+"' instead of the output or '"+ChangesAlongside+"' it")
+	_ = flag.String(FlagChangesStateFile, "", "(optional) File where the output is stored to compare it with the one of the following run")
+	_ = flag.Duration(FlagWatchInterval, 0, "(optional, default 0) Keep running, discovering and printing the output every interval. 0 discovers once")
+	_ = flag.String(FlagOutputFormat, OutputFormatJSON, "(optional, default "+OutputFormatJSON+") Format the output is printed in, one of '"+
+		strings.Join(outputFormats[:len(outputFormats)-1], "', '")+"' or '"+outputFormats[len(outputFormats)-1]+"'")
+	_ = flag.String(FlagOutputFile, "", "(optional) File the output is also written to, replacing it atomically so readers never see it partially written")
+	_ = flag.String(FlagOutputDir, "", "(optional) Directory where each item is also written to its own JSON file named by its discoveryID. Files of items no longer discovered are removed")
+	_ = flag.String(FlagPrometheusPortName, "", "(optional) Name of the port scraped by Prometheus when the prometheus.io/port annotation is not set. Every port is a target if not set")
+	_ = flag.String(FlagWebhookURL, "", "(optional) URL the output, or its changes, is posted to as JSON after every run")
+	_ = flag.String(FlagWebhookBearerTokenFile, "", "(optional) File holding the bearer token sent to the webhook")
+	_ = flag.String(FlagWebhookHMACSecretFile, "", "(optional) File holding the secret used to sign webhook payloads with HMAC-SHA256 in the X-Discovery-Signature header")
+	_ = flag.String(FlagWebhookPayload, WebhookPayloadOutput, "(optional, default "+WebhookPayloadOutput+") What is posted to the webhook, either '"+
+		WebhookPayloadOutput+"' or '"+WebhookPayloadChanges+"', which requires a watch interval")
+	_ = flag.Int(FlagWebhookQueueSize, DefaultWebhookQueueSize, "(optional, default 10) Payloads waiting to be posted to the webhook, the oldest ones are dropped when full")
+	_ = flag.String(FlagRulesFile, "", "(optional) YAML file declaring rules matching discovered items to integrations, listed in the matchedRules variable of each item")
+	_ = flag.Bool(FlagRulesOnly, false, "(optional, default false) Print only the items matching at least one of the rules declared in the rules file")
+	_ = flag.Bool(FlagDiscoverNodes, false, "(optional, default false) Discover the local node, or every node if node_name is not set, instead of pods")
+	_ = flag.Bool(FlagRBACPreflight, false, "(optional, default false) Check the permissions of the service account before each discovery, skipping the features not permitted")
 
-	ErrClusterNameNotSet = errors.New("cluster name is not set")
+	ErrClusterNameNotSet          = errors.New("cluster name is not set")
+	ErrConflictingDiscoveryModes  = errors.New("only one of discover-services, discover-custom-resources and discover-nodes can be set")
+	ErrInvalidBackoff             = errors.New("backoff must be one of " + retry.BackoffLinear + ", " + retry.BackoffExponential + " or " + retry.BackoffExponentialJitter)
+	ErrInvalidPodsSource          = errors.New("pods source must be either " + PodsSourceKubelet + " or " + PodsSourceAPIServer)
+	ErrInvalidKubeletScheme       = errors.New("kubelet scheme must be either http or https")
+	ErrIncompleteKubeletClientTLS = errors.New("kubelet client certificate and key must be set together")
+	ErrInvalidServicesPageSize    = errors.New("services page size cannot be negative")
+	ErrInvalidServicesWorkers     = errors.New("services workers must be at least 1")
+	ErrInvalidChanges             = errors.New("changes must be one of " + ChangesNone + ", " + ChangesOnly + " or " + ChangesAlongside)
+	ErrChangesWithoutPrevious     = errors.New("printing changes requires either a changes state file or a watch interval")
+	ErrInvalidOutputFormat        = errors.New("output format must be one of " + strings.Join(outputFormats, ", "))
+	ErrChangesRequireJSON         = errors.New("changes can only be printed in " + OutputFormatJSON + ", " + OutputFormatJSONPretty + " or " + OutputFormatYAML + " output formats")
+	ErrCustomResourcesNotDeclared = errors.New("discovering custom resources requires at least one resource declared in the custom resources file")
+	ErrInvalidWebhookPayload      = errors.New("webhook payload must be either " + WebhookPayloadOutput + " or " + WebhookPayloadChanges)
+	ErrInvalidWebhookQueueSize    = errors.New("webhook queue size must be at least 1")
+	ErrWebhookChangesWithoutWatch = errors.New("posting changes to the webhook requires a watch interval")
+	ErrRulesNotDeclared           = errors.New("printing only matched items requires at least one rule declared in the rules file")
+	ErrConflictingContexts        = errors.New("only one of context and contexts can be set")
+	ErrConflictingAPIServer       = errors.New("only one of kube-api-server and contexts can be set")
+	ErrInvalidContexts            = errors.New("contexts must be a comma separated list of context or context=cluster_name")
 )
 
 // Config defined the currently accepted configuration parameters of the Discoverer.
@@ -61,11 +211,123 @@ type Config struct {
 	Host             string
 	TLS              bool
 	Timeout          int
+	Deadline         int
 	Retries          int
+	Backoff          string
+	MaxBackoff       time.Duration
+	Strict           bool
 	KubeConfigFile   string
 	ClusterName      string
 	NodeName         string
 	DiscoverServices bool
+	ServicesPageSize int
+	ServicesWorkers  int
+
+	KubeContext   string
+	KubeAPIServer string
+	// Contexts are discovered one after another when set, instead of the cluster configured.
+	Contexts []ClusterContext
+
+	DiscoverCustomResources bool
+	CustomResources         []CustomResource
+
+	DiscoverNodes bool
+
+	PodsSource        string
+	APIServerFallback bool
+
+	RBACPreflight bool
+	// SkipServiceNamespaces are the namespaces whose services are not listed, as the RBAC preflight found it forbidden.
+	SkipServiceNamespaces []string
+
+	KubeletScheme             string
+	KubeletCAFile             string
+	KubeletClientCert         string
+	KubeletClientKey          string
+	KubeletServerName         string
+	KubeletInsecureSkipVerify bool
+
+	ConnectionCacheFile string
+	ConnectionCacheTTL  time.Duration
+
+	OutputCacheFile   string
+	OutputCacheMaxAge time.Duration
+
+	Changes          string
+	ChangesStateFile string
+	WatchInterval    time.Duration
+
+	OutputFormat       string
+	PrometheusPortName string
+	OutputFile         string
+	OutputDir          string
+
+	WebhookURL         string
+	WebhookBearerToken string
+	WebhookHMACSecret  string
+	WebhookPayload     string
+	WebhookQueueSize   int
+
+	Rules     []Rule
+	RulesOnly bool
+
+	ConfigFile       string
+	Selectors        Selectors
+	AnnotationFilter AnnotationFilter
+	EntityRewrites   EntityRewriteTemplates
+}
+
+// ClusterContext is a kubeconfig context to discover along with the name of its cluster.
+type ClusterContext struct {
+	Context     string
+	ClusterName string
+}
+
+// CustomResource declares a custom resource to discover and how its fields are mapped into variables.
+type CustomResource struct {
+	// APIVersion is the group/version of the resource, e.g. kafka.strimzi.io/v1beta2.
+	APIVersion string `json:"apiVersion"`
+	// Resource is the plural resource name, e.g. kafkas.
+	Resource string `json:"resource"`
+	// ClusterScoped resources are listed once, ignoring the configured namespaces.
+	ClusterScoped bool `json:"clusterScoped,omitempty"`
+	// LabelSelector restricts the objects discovered.
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Variables maps variable names to JSONPath expressions evaluated against each object.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// Rule matches discovered items to the integration run for them. Items match when every matcher set matches.
+type Rule struct {
+	// Name identifies the rule in the matchedRules variable.
+	Name string `json:"name"`
+	// Integration is the name of the integration run for the matching items, e.g. nri-redis.
+	Integration string `json:"integration"`
+	// Variables are added to the matching items along with the integration.
+	Variables map[string]string `json:"variables,omitempty"`
+	Match     RuleMatch         `json:"match"`
+}
+
+// RuleMatch declares the matchers of a rule.
+type RuleMatch struct {
+	// Image is a regular expression matching the container image.
+	Image string `json:"image,omitempty"`
+	// LabelSelector is a Kubernetes label selector, e.g. app in (redis,valkey).
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// PortName matches items exposing a port with this name.
+	PortName string `json:"portName,omitempty"`
+	// Annotations maps annotation names to regular expressions matching their values.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// rulesFile is the format of the file passed through FlagRulesFile.
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// customResourcesFile is the format of the file passed through FlagCustomResourcesFile.
+type customResourcesFile struct {
+	CustomResources []CustomResource `json:"customResources"`
 }
 
 func splitStrings(str string) []string {
@@ -75,6 +337,32 @@ func splitStrings(str string) []string {
 	return []string{}
 }
 
+// parseContexts parses a comma separated list of context=cluster_name, where the cluster name defaults to the context.
+func parseContexts(str string) ([]ClusterContext, error) {
+	var contexts []ClusterContext
+	for _, item := range splitStrings(str) {
+		context, cluster, hasCluster := strings.Cut(strings.TrimSpace(item), "=")
+		if !hasCluster {
+			cluster = context
+		}
+		if context == "" || cluster == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidContexts, item)
+		}
+		contexts = append(contexts, ClusterContext{Context: context, ClusterName: cluster})
+	}
+	return contexts, nil
+}
+
+func countTrue(values ...bool) int {
+	count := 0
+	for _, v := range values {
+		if v {
+			count++
+		}
+	}
+	return count
+}
+
 // IsFlagPassed checks if a particular command line argument was provided or not.
 func IsFlagPassed(name string) bool {
 	found := false
@@ -88,32 +376,252 @@ func IsFlagPassed(name string) bool {
 
 // NewConfig generates Config from flags.
 func NewConfig(version string) (*Config, error) {
-	flag.Parse()
+	// Flags are parsed once, so the configuration can be read again when the configuration file changes.
+	if !flag.Parsed() {
+		flag.Parse()
+	}
 
 	v := viper.New()
+	_ = v.BindPFlag(FlagConfig, flag.Lookup(FlagConfig))
 	_ = v.BindPFlag(FlagNamespaces, flag.Lookup(FlagNamespaces))
 	_ = v.BindPFlag(FlagPort, flag.Lookup(FlagPort))
 	_ = v.BindPFlag(FlagHost, flag.Lookup(FlagHost))
 	_ = v.BindPFlag(FlagTLS, flag.Lookup(FlagTLS))
 	_ = v.BindPFlag(FlagInsecure, flag.Lookup(FlagInsecure))
 	_ = v.BindPFlag(FlagTimeout, flag.Lookup(FlagTimeout))
+	_ = v.BindPFlag(FlagDeadline, flag.Lookup(FlagDeadline))
 	_ = v.BindPFlag(FlagRetries, flag.Lookup(FlagRetries))
+	_ = v.BindPFlag(FlagBackoff, flag.Lookup(FlagBackoff))
+	_ = v.BindPFlag(FlagStrict, flag.Lookup(FlagStrict))
+	_ = v.BindPFlag(FlagMaxBackoff, flag.Lookup(FlagMaxBackoff))
 	_ = v.BindPFlag(FlagKubeConfigFile, flag.Lookup(FlagKubeConfigFile))
+	_ = v.BindPFlag(FlagKubeContext, flag.Lookup(FlagKubeContext))
+	_ = v.BindPFlag(FlagKubeAPIServer, flag.Lookup(FlagKubeAPIServer))
+	_ = v.BindPFlag(FlagContexts, flag.Lookup(FlagContexts))
 
 	_ = v.BindPFlag(FlagClusterName, flag.Lookup(FlagClusterName))
 	_ = v.BindPFlag(FlagNodeName, flag.Lookup(FlagNodeName))
 	_ = v.BindPFlag(FlagDiscoverServices, flag.Lookup(FlagDiscoverServices))
+	_ = v.BindPFlag(FlagServicesPageSize, flag.Lookup(FlagServicesPageSize))
+	_ = v.BindPFlag(FlagServicesWorkers, flag.Lookup(FlagServicesWorkers))
+	_ = v.BindPFlag(FlagDiscoverCustomResources, flag.Lookup(FlagDiscoverCustomResources))
+	_ = v.BindPFlag(FlagCustomResourcesFile, flag.Lookup(FlagCustomResourcesFile))
+	_ = v.BindPFlag(FlagDiscoverNodes, flag.Lookup(FlagDiscoverNodes))
+	_ = v.BindPFlag(FlagPodsSource, flag.Lookup(FlagPodsSource))
+	_ = v.BindPFlag(FlagAPIServerFallback, flag.Lookup(FlagAPIServerFallback))
+	_ = v.BindPFlag(FlagKubeletScheme, flag.Lookup(FlagKubeletScheme))
+	_ = v.BindPFlag(FlagKubeletCAFile, flag.Lookup(FlagKubeletCAFile))
+	_ = v.BindPFlag(FlagKubeletClientCert, flag.Lookup(FlagKubeletClientCert))
+	_ = v.BindPFlag(FlagKubeletClientKey, flag.Lookup(FlagKubeletClientKey))
+	_ = v.BindPFlag(FlagKubeletServerName, flag.Lookup(FlagKubeletServerName))
+	_ = v.BindPFlag(FlagKubeletInsecureSkipVerify, flag.Lookup(FlagKubeletInsecureSkipVerify))
+	_ = v.BindPFlag(FlagConnectionCacheFile, flag.Lookup(FlagConnectionCacheFile))
+	_ = v.BindPFlag(FlagConnectionCacheTTL, flag.Lookup(FlagConnectionCacheTTL))
+	_ = v.BindPFlag(FlagOutputCacheFile, flag.Lookup(FlagOutputCacheFile))
+	_ = v.BindPFlag(FlagOutputCacheMaxAge, flag.Lookup(FlagOutputCacheMaxAge))
+	_ = v.BindPFlag(FlagChanges, flag.Lookup(FlagChanges))
+	_ = v.BindPFlag(FlagChangesStateFile, flag.Lookup(FlagChangesStateFile))
+	_ = v.BindPFlag(FlagWatchInterval, flag.Lookup(FlagWatchInterval))
+	_ = v.BindPFlag(FlagOutputFormat, flag.Lookup(FlagOutputFormat))
+	_ = v.BindPFlag(FlagPrometheusPortName, flag.Lookup(FlagPrometheusPortName))
+	_ = v.BindPFlag(FlagOutputFile, flag.Lookup(FlagOutputFile))
+	_ = v.BindPFlag(FlagOutputDir, flag.Lookup(FlagOutputDir))
+	_ = v.BindPFlag(FlagWebhookURL, flag.Lookup(FlagWebhookURL))
+	_ = v.BindPFlag(FlagWebhookBearerTokenFile, flag.Lookup(FlagWebhookBearerTokenFile))
+	_ = v.BindPFlag(FlagWebhookHMACSecretFile, flag.Lookup(FlagWebhookHMACSecretFile))
+	_ = v.BindPFlag(FlagWebhookPayload, flag.Lookup(FlagWebhookPayload))
+	_ = v.BindPFlag(FlagWebhookQueueSize, flag.Lookup(FlagWebhookQueueSize))
+	_ = v.BindPFlag(FlagRulesFile, flag.Lookup(FlagRulesFile))
+	_ = v.BindPFlag(FlagRulesOnly, flag.Lookup(FlagRulesOnly))
+	_ = v.BindPFlag(FlagRBACPreflight, flag.Lookup(FlagRBACPreflight))
 
 	v.SetEnvPrefix(envPrefix)
 	v.AutomaticEnv()
 
+	file := &configFile{}
+	if path := v.GetString(FlagConfig); path != "" {
+		var err error
+		if file, err = readConfigFile(path); err != nil {
+			return &Config{}, err
+		}
+		// Values of the file are taken only if neither the flag nor the environment variable are set.
+		if err := v.MergeConfigMap(file.flags); err != nil {
+			return &Config{}, fmt.Errorf("reading configuration file %q: %w", path, err)
+		}
+	}
+
 	config := Config{
 		Namespaces:       splitStrings(v.GetString(FlagNamespaces)),
 		Port:             v.GetInt(FlagPort),
 		Host:             v.GetString(FlagHost),
 		Timeout:          v.GetInt(FlagTimeout),
+		Deadline:         v.GetInt(FlagDeadline),
 		Retries:          v.GetInt(FlagRetries),
+		Backoff:          v.GetString(FlagBackoff),
+		MaxBackoff:       v.GetDuration(FlagMaxBackoff),
+		Strict:           v.GetBool(FlagStrict),
 		DiscoverServices: v.GetBool(FlagDiscoverServices),
+		ServicesPageSize: v.GetInt(FlagServicesPageSize),
+		ServicesWorkers:  v.GetInt(FlagServicesWorkers),
+
+		DiscoverCustomResources: v.GetBool(FlagDiscoverCustomResources),
+		DiscoverNodes:           v.GetBool(FlagDiscoverNodes),
+		PodsSource:              v.GetString(FlagPodsSource),
+		APIServerFallback:       v.GetBool(FlagAPIServerFallback),
+		RBACPreflight:           v.GetBool(FlagRBACPreflight),
+
+		KubeContext:   v.GetString(FlagKubeContext),
+		KubeAPIServer: v.GetString(FlagKubeAPIServer),
+
+		KubeletScheme:             v.GetString(FlagKubeletScheme),
+		KubeletCAFile:             v.GetString(FlagKubeletCAFile),
+		KubeletClientCert:         v.GetString(FlagKubeletClientCert),
+		KubeletClientKey:          v.GetString(FlagKubeletClientKey),
+		KubeletServerName:         v.GetString(FlagKubeletServerName),
+		KubeletInsecureSkipVerify: v.GetBool(FlagKubeletInsecureSkipVerify),
+
+		ConnectionCacheFile: v.GetString(FlagConnectionCacheFile),
+		ConnectionCacheTTL:  v.GetDuration(FlagConnectionCacheTTL),
+
+		OutputCacheFile:   v.GetString(FlagOutputCacheFile),
+		OutputCacheMaxAge: v.GetDuration(FlagOutputCacheMaxAge),
+
+		Changes:          v.GetString(FlagChanges),
+		ChangesStateFile: v.GetString(FlagChangesStateFile),
+		WatchInterval:    v.GetDuration(FlagWatchInterval),
+
+		OutputFormat:       v.GetString(FlagOutputFormat),
+		PrometheusPortName: v.GetString(FlagPrometheusPortName),
+		OutputFile:         v.GetString(FlagOutputFile),
+		OutputDir:          v.GetString(FlagOutputDir),
+
+		WebhookURL:       v.GetString(FlagWebhookURL),
+		WebhookPayload:   v.GetString(FlagWebhookPayload),
+		WebhookQueueSize: v.GetInt(FlagWebhookQueueSize),
+
+		RulesOnly: v.GetBool(FlagRulesOnly),
+
+		ConfigFile:       v.GetString(FlagConfig),
+		Selectors:        file.selectors,
+		AnnotationFilter: file.annotations,
+		EntityRewrites:   file.entityRewrites,
+		CustomResources:  file.customResources,
+		Rules:            file.rules,
+	}
+
+	if config.KubeletScheme != "" && config.KubeletScheme != "http" && config.KubeletScheme != "https" {
+		return &Config{}, ErrInvalidKubeletScheme
+	}
+
+	contexts, err := parseContexts(v.GetString(FlagContexts))
+	if err != nil {
+		return &Config{}, err
+	}
+	if len(contexts) > 0 && config.KubeContext != "" {
+		return &Config{}, ErrConflictingContexts
+	}
+	if len(contexts) > 0 && config.KubeAPIServer != "" {
+		return &Config{}, ErrConflictingAPIServer
+	}
+	config.Contexts = contexts
+
+	if (config.KubeletClientCert == "") != (config.KubeletClientKey == "") {
+		return &Config{}, ErrIncompleteKubeletClientTLS
+	}
+
+	if !retry.IsValidBackoff(config.Backoff) {
+		return &Config{}, ErrInvalidBackoff
+	}
+
+	if config.ServicesPageSize < 0 {
+		return &Config{}, ErrInvalidServicesPageSize
+	}
+
+	if config.ServicesWorkers < 1 {
+		return &Config{}, ErrInvalidServicesWorkers
+	}
+
+	if config.Changes != ChangesNone && config.Changes != ChangesOnly && config.Changes != ChangesAlongside {
+		return &Config{}, ErrInvalidChanges
+	}
+
+	if config.Changes != ChangesNone && config.ChangesStateFile == "" && config.WatchInterval <= 0 {
+		return &Config{}, ErrChangesWithoutPrevious
+	}
+
+	if !slices.Contains(outputFormats, config.OutputFormat) {
+		return &Config{}, ErrInvalidOutputFormat
+	}
+
+	switch config.OutputFormat {
+	case OutputFormatJSON, OutputFormatJSONPretty, OutputFormatYAML:
+	default:
+		if config.Changes != ChangesNone {
+			return &Config{}, ErrChangesRequireJSON
+		}
+	}
+
+	if config.PodsSource != PodsSourceKubelet && config.PodsSource != PodsSourceAPIServer {
+		return &Config{}, ErrInvalidPodsSource
+	}
+
+	if countTrue(config.DiscoverServices, config.DiscoverCustomResources, config.DiscoverNodes) > 1 {
+		return &Config{}, ErrConflictingDiscoveryModes
+	}
+
+	if file := v.GetString(FlagCustomResourcesFile); file != "" {
+		resources, err := readCustomResources(file)
+		if err != nil {
+			return &Config{}, err
+		}
+		config.CustomResources = append(config.CustomResources, resources...)
+	}
+
+	if config.DiscoverCustomResources && len(config.CustomResources) == 0 {
+		return &Config{}, ErrCustomResourcesNotDeclared
+	}
+
+	if file := v.GetString(FlagRulesFile); file != "" {
+		rules, err := readRules(file)
+		if err != nil {
+			return &Config{}, err
+		}
+		config.Rules = append(config.Rules, rules...)
+		if err := validateRules(config.Rules); err != nil {
+			return &Config{}, fmt.Errorf("rules declared in the rules and configuration files: %w", err)
+		}
+	}
+
+	if config.RulesOnly && len(config.Rules) == 0 {
+		return &Config{}, ErrRulesNotDeclared
+	}
+
+	if config.WebhookPayload != WebhookPayloadOutput && config.WebhookPayload != WebhookPayloadChanges {
+		return &Config{}, ErrInvalidWebhookPayload
+	}
+
+	if config.WebhookPayload == WebhookPayloadChanges && config.WatchInterval <= 0 {
+		return &Config{}, ErrWebhookChangesWithoutWatch
+	}
+
+	if config.WebhookQueueSize < 1 {
+		return &Config{}, ErrInvalidWebhookQueueSize
+	}
+
+	if file := v.GetString(FlagWebhookBearerTokenFile); file != "" {
+		token, err := readSecret(file)
+		if err != nil {
+			return &Config{}, err
+		}
+		config.WebhookBearerToken = token
+	}
+
+	if file := v.GetString(FlagWebhookHMACSecretFile); file != "" {
+		secret, err := readSecret(file)
+		if err != nil {
+			return &Config{}, err
+		}
+		config.WebhookHMACSecret = secret
 	}
 
 	// To leave the variable empty as nil
@@ -126,7 +634,8 @@ func NewConfig(version string) (*Config, error) {
 	if !isClusterNameSet {
 		cluster = v.GetString(FlagClusterName)
 	}
-	if cluster == "" {
+	// Cluster names are taken from the contexts when several are discovered.
+	if cluster == "" && len(config.Contexts) == 0 {
 		return &Config{}, ErrClusterNameNotSet
 	}
 	config.ClusterName = cluster
@@ -152,3 +661,80 @@ func NewConfig(version string) (*Config, error) {
 
 	return &config, nil
 }
+
+func readCustomResources(file string) ([]CustomResource, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading custom resources file %q: %w", file, err)
+	}
+
+	crFile := customResourcesFile{}
+	if err := yaml.UnmarshalStrict(content, &crFile); err != nil {
+		return nil, fmt.Errorf("parsing custom resources file %q: %w", file, err)
+	}
+
+	for i, cr := range crFile.CustomResources {
+		if err := validateCustomResource(cr); err != nil {
+			return nil, fmt.Errorf("custom resource #%d in %q: %w", i, file, err)
+		}
+	}
+
+	return crFile.CustomResources, nil
+}
+
+func validateCustomResource(cr CustomResource) error {
+	if cr.APIVersion == "" || cr.Resource == "" {
+		return errors.New("apiVersion and resource are required")
+	}
+	return nil
+}
+
+func readRules(file string) ([]Rule, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file %q: %w", file, err)
+	}
+
+	rFile := rulesFile{}
+	if err := yaml.UnmarshalStrict(content, &rFile); err != nil {
+		return nil, fmt.Errorf("parsing rules file %q: %w", file, err)
+	}
+
+	if err := validateRules(rFile.Rules); err != nil {
+		return nil, fmt.Errorf("rules file %q: %w", file, err)
+	}
+
+	return rFile.Rules, nil
+}
+
+func validateRules(rules []Rule) error {
+	names := map[string]bool{}
+	for i, rule := range rules {
+		if rule.Name == "" || rule.Integration == "" {
+			return fmt.Errorf("rule #%d: name and integration are required", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule #%d: name %q is already used", i, rule.Name)
+		}
+		names[rule.Name] = true
+		if _, found := rule.Variables["integration"]; found {
+			return fmt.Errorf("rule %q: variable name integration is reserved", rule.Name)
+		}
+
+		m := rule.Match
+		if m.Image == "" && m.LabelSelector == "" && m.PortName == "" && len(m.Annotations) == 0 {
+			return fmt.Errorf("rule %q: at least one matcher is required", rule.Name)
+		}
+	}
+
+	return nil
+}
+
+// readSecret returns the content of the file without surrounding whitespace, e.g. the trailing new line.
+func readSecret(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file %q: %w", file, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
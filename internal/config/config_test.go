@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContexts(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []ClusterContext
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{
+			name:  "cluster_names",
+			value: "prod-eu=production, staging",
+			want: []ClusterContext{
+				{Context: "prod-eu", ClusterName: "production"},
+				{Context: "staging", ClusterName: "staging"},
+			},
+		},
+		{name: "missing_context", value: "=production", wantErr: true},
+		{name: "missing_cluster_name", value: "prod-eu=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contexts, err := parseContexts(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidContexts)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, contexts)
+		})
+	}
+}
+
+func TestNewConfig_Contexts(t *testing.T) {
+	setFlag(t, FlagContexts, "prod-eu=production")
+	setFlag(t, FlagClusterName, "")
+
+	c, err := NewConfig("test")
+	require.NoError(t, err, "Cluster name is not required when it is set for each context")
+	assert.Equal(t, []ClusterContext{{Context: "prod-eu", ClusterName: "production"}}, c.Contexts)
+
+	setFlag(t, FlagKubeAPIServer, "https://127.0.0.1:6443")
+	_, err = NewConfig("test")
+	assert.ErrorIs(t, err, ErrConflictingAPIServer)
+
+	setFlag(t, FlagKubeAPIServer, "")
+	setFlag(t, FlagKubeContext, "staging")
+	_, err = NewConfig("test")
+	assert.ErrorIs(t, err, ErrConflictingContexts)
+}
+
+func TestNewConfig_WebhookChanges(t *testing.T) {
+	setFlag(t, FlagClusterName, "cluster")
+	setFlag(t, FlagWebhookPayload, WebhookPayloadChanges)
+
+	_, err := NewConfig("test")
+	assert.ErrorIs(t, err, ErrWebhookChangesWithoutWatch, "Changes are only posted from the previous payload while watching")
+
+	setFlag(t, FlagWatchInterval, "30s")
+	_, err = NewConfig("test")
+	assert.NoError(t, err)
+}
+
+func TestNewConfig_APIServerFallback(t *testing.T) {
+	setFlag(t, FlagClusterName, "cluster")
+
+	c, err := NewConfig("test")
+	require.NoError(t, err)
+	assert.True(t, c.APIServerFallback, "Pods are listed from the API server by default when the kubelet is not reachable")
+
+	setFlag(t, FlagAPIServerFallback, "false")
+	c, err = NewConfig("test")
+	require.NoError(t, err)
+	assert.False(t, c.APIServerFallback)
+}
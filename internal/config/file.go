@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Keys of the configuration file holding the options flags cannot express. The rest of keys are named as the flags.
+const (
+	keySelectors       = "selectors"
+	keyAnnotations     = "annotations"
+	keyEntityRewrites  = "entityRewrites"
+	keyCustomResources = "customResources"
+	keyRules           = "rules"
+)
+
+var (
+	ErrUnknownConfigKey   = errors.New("unknown key")
+	ErrInvalidConfigValue = errors.New("invalid value of key")
+)
+
+// Selectors restrict the items discovered.
+type Selectors struct {
+	// Labels is a Kubernetes label selector matching the labels of the items discovered, e.g. app.kubernetes.io/part-of=shop.
+	Labels string `json:"labels,omitempty"`
+}
+
+// AnnotationFilter selects by name the annotations added to the variables of the items.
+type AnnotationFilter struct {
+	// Include lists regular expressions matching the annotations kept. Every annotation is kept if empty.
+	Include []string `json:"include,omitempty"`
+	// Exclude lists regular expressions matching the annotations dropped, even if included.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// EntityRewriteTemplates replace the entity names the items of each kind are rewritten to,
+// e.g. k8s:${clusterName}:${namespace}:pod:${podName}:${name}. Kinds without template keep the default one.
+type EntityRewriteTemplates struct {
+	Pod            string `json:"pod,omitempty"`
+	Service        string `json:"service,omitempty"`
+	Node           string `json:"node,omitempty"`
+	CustomResource string `json:"customResource,omitempty"`
+}
+
+// configFile is the content of the file passed through FlagConfig.
+type configFile struct {
+	// flags holds the values of the keys named as flags, converted to the type of the flag.
+	flags           map[string]interface{}
+	selectors       Selectors
+	annotations     AnnotationFilter
+	entityRewrites  EntityRewriteTemplates
+	customResources []CustomResource
+	rules           []Rule
+}
+
+func readConfigFile(file string) (*configFile, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration file %q: %w", file, err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("parsing configuration file %q: %w", file, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// Keys are checked in order, so the same error is always reported first.
+	sort.Strings(keys)
+
+	cf := &configFile{flags: map[string]interface{}{}}
+	for _, key := range keys {
+		value := values[key]
+
+		var err error
+		switch key {
+		case keySelectors:
+			err = decodeKey(value, &cf.selectors)
+		case keyAnnotations:
+			err = decodeKey(value, &cf.annotations)
+		case keyEntityRewrites:
+			err = decodeKey(value, &cf.entityRewrites)
+		case keyCustomResources:
+			err = decodeKey(value, &cf.customResources)
+		case keyRules:
+			err = decodeKey(value, &cf.rules)
+		default:
+			f := flag.Lookup(key)
+			if f == nil || key == FlagConfig {
+				return nil, fmt.Errorf("configuration file %q: %w %q%s", file, ErrUnknownConfigKey, key, suggestKey(key))
+			}
+			cf.flags[key], err = flagValue(f, value)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("configuration file %q: %w %q: %v", file, ErrInvalidConfigValue, key, err)
+		}
+	}
+
+	if err := cf.validate(); err != nil {
+		return nil, fmt.Errorf("configuration file %q: %w", file, err)
+	}
+
+	return cf, nil
+}
+
+// validate checks the values of the keys flags cannot express, naming the key of the invalid ones.
+func (cf *configFile) validate() error {
+	if cf.selectors.Labels != "" {
+		if _, err := labels.Parse(cf.selectors.Labels); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidConfigValue, keySelectors+".labels", err)
+		}
+	}
+
+	for name, expressions := range map[string][]string{"include": cf.annotations.Include, "exclude": cf.annotations.Exclude} {
+		for i, expression := range expressions {
+			if _, err := regexp.Compile(expression); err != nil {
+				return fmt.Errorf("%w %q: %v", ErrInvalidConfigValue, fmt.Sprintf("%s.%s[%d]", keyAnnotations, name, i), err)
+			}
+		}
+	}
+
+	for i, cr := range cf.customResources {
+		if err := validateCustomResource(cr); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidConfigValue, fmt.Sprintf("%s[%d]", keyCustomResources, i), err)
+		}
+	}
+
+	if err := validateRules(cf.rules); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidConfigValue, keyRules, err)
+	}
+
+	return nil
+}
+
+// decodeKey decodes the value of a key into the target, failing on unknown fields.
+func decodeKey(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// flagValue converts the value of a key named as the flag to the flag type.
+func flagValue(f *flag.Flag, value interface{}) (interface{}, error) {
+	switch f.Value.Type() {
+	case "bool":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, errors.New("expected true or false")
+	case "int":
+		if n, ok := value.(float64); ok && n == math.Trunc(n) {
+			return int(n), nil
+		}
+		return nil, errors.New("expected an integer")
+	case "duration":
+		if s, ok := value.(string); ok {
+			if _, err := time.ParseDuration(s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, errors.New("expected a duration, e.g. 30s")
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64, bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		// Namespaces and contexts can be listed instead of comma separated.
+		if f.Name == FlagNamespaces || f.Name == FlagContexts {
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			return strings.Join(items, ","), nil
+		}
+	}
+	return nil, errors.New("expected a string")
+}
+
+// suggestKey returns a hint naming the key meant when it only differs in using dashes or underscores, e.g. cluster-name.
+func suggestKey(key string) string {
+	for _, candidate := range []string{strings.ReplaceAll(key, "_", "-"), strings.ReplaceAll(key, "-", "_")} {
+		if candidate != key && flag.Lookup(candidate) != nil {
+			return fmt.Sprintf(", did you mean %q?", candidate)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func TestReadConfigFile(t *testing.T) {
+	file := writeConfigFile(t, `
+cluster_name: test-cluster
+namespaces: [default, kube-system]
+contexts: [prod-eu=production, staging]
+discover-services: true
+timeout: 2000
+watch-interval: 30s
+selectors:
+  labels: app.kubernetes.io/part-of=shop
+annotations:
+  include: ['^prometheus\.io/']
+  exclude: ['scrape$']
+entityRewrites:
+  pod: k8s:${clusterName}:${podName}
+rules:
+  - name: redis
+    integration: nri-redis
+    match:
+      image: ^redis
+`)
+
+	cf, err := readConfigFile(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		FlagClusterName:      "test-cluster",
+		FlagNamespaces:       "default,kube-system",
+		FlagContexts:         "prod-eu=production,staging",
+		FlagDiscoverServices: true,
+		FlagTimeout:          2000,
+		FlagWatchInterval:    "30s",
+	}, cf.flags)
+	assert.Equal(t, Selectors{Labels: "app.kubernetes.io/part-of=shop"}, cf.selectors)
+	assert.Equal(t, AnnotationFilter{Include: []string{`^prometheus\.io/`}, Exclude: []string{"scrape$"}}, cf.annotations)
+	assert.Equal(t, EntityRewriteTemplates{Pod: "k8s:${clusterName}:${podName}"}, cf.entityRewrites)
+	require.Len(t, cf.rules, 1)
+	assert.Equal(t, "nri-redis", cf.rules[0].Integration)
+}
+
+func TestReadConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+		wantKey string
+	}{
+		{name: "unknown_key", content: "unknown: true", wantErr: ErrUnknownConfigKey, wantKey: `"unknown"`},
+		{name: "suggested_key", content: "cluster-name: test", wantErr: ErrUnknownConfigKey, wantKey: `did you mean "cluster_name"?`},
+		{name: "config_key", content: "config: other.yaml", wantErr: ErrUnknownConfigKey, wantKey: `"config"`},
+		{name: "bool", content: "discover-services: yes please", wantErr: ErrInvalidConfigValue, wantKey: `"discover-services"`},
+		{name: "int", content: "timeout: 1.5", wantErr: ErrInvalidConfigValue, wantKey: `"timeout"`},
+		{name: "duration", content: "watch-interval: 30", wantErr: ErrInvalidConfigValue, wantKey: `"watch-interval"`},
+		{name: "string", content: "cluster_name: [a, b]", wantErr: ErrInvalidConfigValue, wantKey: `"cluster_name"`},
+		{name: "unknown_field", content: "selectors:\n  label: app=shop", wantErr: ErrInvalidConfigValue, wantKey: `"selectors"`},
+		{name: "label_selector", content: "selectors:\n  labels: app in (shop", wantErr: ErrInvalidConfigValue, wantKey: `"selectors.labels"`},
+		{name: "annotation_regexp", content: "annotations:\n  exclude: ['a', '[']", wantErr: ErrInvalidConfigValue, wantKey: `"annotations.exclude[1]"`},
+		{name: "custom_resource", content: "customResources:\n  - resource: kafkas", wantErr: ErrInvalidConfigValue, wantKey: `"customResources[0]"`},
+		{name: "rules", content: "rules:\n  - name: redis", wantErr: ErrInvalidConfigValue, wantKey: `"rules"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readConfigFile(writeConfigFile(t, tt.content))
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Contains(t, err.Error(), tt.wantKey)
+		})
+	}
+}
+
+func TestReadConfigFile_NotFound(t *testing.T) {
+	_, err := readConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Sources of the settings.
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceLegacy  = "legacy"
+)
+
+const (
+	// Port 0 reads the kubelet port from the node status.
+	minPort = 0
+	maxPort = 65535
+)
+
+// Setting is the effective value of an option along with where it was taken from.
+type Setting struct {
+	Name  string
+	Value string
+	// Source is one of the Source constants.
+	Source string
+	// Origin details the source, e.g. the environment variable or file the value was read from.
+	Origin string
+}
+
+// Settings returns the effective value of every option, following the same precedence as NewConfig: flags,
+// NRIA_* environment variables, the configuration file and defaults, overridden by the legacy environment variables.
+func Settings() ([]Setting, error) {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	file, path, err := settingsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []Setting
+	byName := map[string]int{}
+	flag.VisitAll(func(f *flag.Flag) {
+		byName[f.Name] = len(settings)
+		settings = append(settings, flagSetting(f, file, path))
+	})
+
+	// keep compatibility with the old env variables, which take precedence over any other source.
+	if cluster, ok := os.LookupEnv(clusterNameEnvVar); ok {
+		settings[byName[FlagClusterName]] = Setting{Name: FlagClusterName, Value: cluster, Source: SourceLegacy, Origin: clusterNameEnvVar}
+	}
+	for _, env := range []string{nodeNameEnvVar, nodeNameEnvVarLegacy} {
+		if node, ok := os.LookupEnv(env); ok {
+			settings[byName[FlagNodeName]] = Setting{Name: FlagNodeName, Value: node, Source: SourceLegacy, Origin: env}
+		}
+	}
+	// insecure, although deprecated, takes precedence over tls when set.
+	if insecure := settings[byName[FlagInsecure]]; insecure.Source != SourceDefault {
+		isInsecure, _ := strconv.ParseBool(insecure.Value) // nolint: errcheck // as viper casts it
+		settings[byName[FlagTLS]] = Setting{
+			Name:   FlagTLS,
+			Value:  strconv.FormatBool(!isInsecure),
+			Source: SourceLegacy,
+			Origin: FlagInsecure + " from " + describeSource(insecure),
+		}
+	}
+
+	for _, structured := range []struct {
+		key   string
+		value interface{}
+		isSet bool
+	}{
+		{keySelectors, file.selectors, file.selectors != Selectors{}},
+		{keyAnnotations, file.annotations, len(file.annotations.Include)+len(file.annotations.Exclude) > 0},
+		{keyEntityRewrites, file.entityRewrites, file.entityRewrites != EntityRewriteTemplates{}},
+		{keyCustomResources, file.customResources, len(file.customResources) > 0},
+		{keyRules, file.rules, len(file.rules) > 0},
+	} {
+		setting := Setting{Name: structured.key, Source: SourceDefault}
+		if structured.isSet {
+			data, _ := json.Marshal(structured.value) // nolint: errcheck // values were read from YAML
+			setting = Setting{Name: structured.key, Value: string(data), Source: SourceFile, Origin: path}
+		}
+		settings = append(settings, setting)
+	}
+
+	return settings, nil
+}
+
+// Validate reads the configuration as NewConfig does, without contacting Kubernetes, also reporting the values
+// accepted by NewConfig for backwards compatibility which are likely mistakes. Every problem found is returned,
+// along with the configuration read, which is nil if NewConfig fails.
+func Validate(version string) (*Config, []error) {
+	var errs []error
+	c, err := NewConfig(version)
+	if err != nil {
+		c = nil
+		errs = append(errs, err)
+	}
+
+	file, path, err := settingsFile()
+	if err != nil {
+		// NewConfig already fails reading the same file.
+		if len(errs) == 0 {
+			errs = append(errs, err)
+		}
+		return c, errs
+	}
+
+	// The rest of checks are made on the settings, so they are reported even if NewConfig fails.
+	insecure, tls := flagSetting(flag.Lookup(FlagInsecure), file, path), flagSetting(flag.Lookup(FlagTLS), file, path)
+	if insecure.Source != SourceDefault && tls.Source != SourceDefault && insecure.Value == tls.Value {
+		errs = append(errs, fmt.Errorf("%s=%s (%s) conflicts with %s=%s (%s), %s takes precedence",
+			FlagInsecure, insecure.Value, describeSource(insecure), FlagTLS, tls.Value, describeSource(tls), FlagInsecure))
+	}
+
+	port := flagSetting(flag.Lookup(FlagPort), file, path)
+	if p, err := strconv.Atoi(port.Value); err == nil && (p < minPort || p > maxPort) {
+		errs = append(errs, fmt.Errorf("%s %d (%s) is out of range %d-%d", FlagPort, p, describeSource(port), minPort, maxPort))
+	}
+
+	for _, ns := range splitStrings(flagSetting(flag.Lookup(FlagNamespaces), file, path).Value) {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, fmt.Errorf("namespace %q is not valid: %s", ns, msg))
+		}
+	}
+
+	return c, errs
+}
+
+// settingsFile returns the configuration file set, which is empty if none is.
+func settingsFile() (*configFile, string, error) {
+	path := flagSetting(flag.Lookup(FlagConfig), nil, "").Value
+	if path == "" {
+		return &configFile{}, "", nil
+	}
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, path, nil
+}
+
+// flagSetting returns the value of the flag from the source taking precedence.
+func flagSetting(f *flag.Flag, file *configFile, path string) Setting {
+	if f.Changed {
+		return Setting{Name: f.Name, Value: f.Value.String(), Source: SourceFlag, Origin: "--" + f.Name}
+	}
+
+	// As viper names the environment variables, e.g. NRIA_CLUSTER_NAME.
+	env := envPrefix + "_" + strings.ToUpper(f.Name)
+	if value, ok := os.LookupEnv(env); ok {
+		return Setting{Name: f.Name, Value: value, Source: SourceEnv, Origin: env}
+	}
+
+	if file != nil {
+		if value, ok := file.flags[f.Name]; ok {
+			return Setting{Name: f.Name, Value: fmt.Sprint(value), Source: SourceFile, Origin: path}
+		}
+	}
+
+	return Setting{Name: f.Name, Value: f.DefValue, Source: SourceDefault}
+}
+
+func describeSource(s Setting) string {
+	if s.Origin == "" {
+		return s.Source
+	}
+	return s.Source + " " + s.Origin
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setFlag sets the flag as if it were given in the command line, restoring it when the test finishes.
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+
+	// Arguments of the test binary are not parsed as flags.
+	if !flag.Parsed() {
+		require.NoError(t, flag.CommandLine.Parse(nil))
+	}
+
+	f := flag.Lookup(name)
+	require.NotNil(t, f)
+	previous := f.Value.String()
+	require.NoError(t, flag.Set(name, value))
+	t.Cleanup(func() {
+		_ = f.Value.Set(previous)
+		f.Changed = false
+	})
+}
+
+func TestSettings(t *testing.T) {
+	setFlag(t, FlagConfig, writeConfigFile(t, `
+cluster_name: file-cluster
+port: 10250
+timeout: 2000
+selectors:
+  labels: tier=backend
+`))
+	setFlag(t, FlagNamespaces, "default")
+	setFlag(t, FlagInsecure, "true")
+	t.Setenv("NRIA_TIMEOUT", "3000")
+	t.Setenv(clusterNameEnvVar, "legacy-cluster")
+	t.Setenv(nodeNameEnvVar, "new-node")
+	t.Setenv(nodeNameEnvVarLegacy, "legacy-node")
+
+	settings, err := Settings()
+	require.NoError(t, err)
+
+	bySetting := map[string]Setting{}
+	for _, s := range settings {
+		bySetting[s.Name] = s
+	}
+
+	assert.Equal(t, Setting{Name: FlagNamespaces, Value: "default", Source: SourceFlag, Origin: "--namespaces"}, bySetting[FlagNamespaces])
+	assert.Equal(t, Setting{Name: FlagTimeout, Value: "3000", Source: SourceEnv, Origin: "NRIA_TIMEOUT"}, bySetting[FlagTimeout], "Environment variables take precedence over the file")
+	assert.Equal(t, SourceFile, bySetting[FlagPort].Source)
+	assert.Equal(t, "10250", bySetting[FlagPort].Value)
+	assert.Equal(t, Setting{Name: FlagHost, Value: DefaultHost, Source: SourceDefault}, bySetting[FlagHost])
+	assert.Equal(t, Setting{Name: FlagClusterName, Value: "legacy-cluster", Source: SourceLegacy, Origin: clusterNameEnvVar}, bySetting[FlagClusterName], "Legacy variable takes precedence over the file")
+	assert.Equal(t, Setting{Name: FlagNodeName, Value: "legacy-node", Source: SourceLegacy, Origin: nodeNameEnvVarLegacy}, bySetting[FlagNodeName], "Oldest variable takes precedence")
+	assert.Equal(t, "false", bySetting[FlagTLS].Value, "Insecure takes precedence over tls")
+	assert.Equal(t, SourceLegacy, bySetting[FlagTLS].Source)
+	assert.Equal(t, `{"labels":"tier=backend"}`, bySetting[keySelectors].Value)
+	assert.Equal(t, SourceFile, bySetting[keySelectors].Source)
+	assert.Equal(t, SourceDefault, bySetting[keyRules].Source)
+}
+
+func TestValidate(t *testing.T) {
+	setFlag(t, FlagClusterName, "test-cluster")
+	setFlag(t, FlagInsecure, "true")
+	setFlag(t, FlagTLS, "true")
+	setFlag(t, FlagPort, "70000")
+	setFlag(t, FlagNamespaces, "default,Kube_System")
+
+	c, errs := Validate("test")
+	require.NotNil(t, c)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "conflicts with tls")
+	assert.Contains(t, errs[1].Error(), "out of range")
+	assert.Contains(t, errs[2].Error(), `"Kube_System"`)
+}
+
+func TestValidate_Valid(t *testing.T) {
+	setFlag(t, FlagClusterName, "test-cluster")
+	setFlag(t, FlagInsecure, "false")
+	setFlag(t, FlagTLS, "true")
+	setFlag(t, FlagPort, "0")
+	setFlag(t, FlagNamespaces, "default,kube-system")
+
+	_, errs := Validate("test")
+	assert.Empty(t, errs, "Port 0 reads the port from the node status")
+}
+
+func TestValidate_ClusterNameNotSet(t *testing.T) {
+	setFlag(t, FlagClusterName, "")
+
+	setFlag(t, FlagPort, "-1")
+	setFlag(t, FlagNamespaces, "Kube_System")
+
+	c, errs := Validate("test")
+	assert.Nil(t, c)
+	require.Len(t, errs, 3, "Settings are still checked if the configuration cannot be read")
+	assert.ErrorIs(t, errs[0], ErrClusterNameNotSet)
+	assert.Contains(t, errs[1].Error(), "out of range")
+	assert.Contains(t, errs[2].Error(), `"Kube_System"`)
+}
@@ -0,0 +1,174 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
+)
+
+// ModifiedItem is an item discovered in both outputs compared whose content changed.
+type ModifiedItem struct {
+	Identity string         `json:"identity"`
+	Previous DiscoveredItem `json:"previous"`
+	Current  DiscoveredItem `json:"current"`
+}
+
+// ChangeSet holds the items added, removed and modified between two outputs.
+type ChangeSet struct {
+	Added    Output         `json:"added"`
+	Removed  Output         `json:"removed"`
+	Modified []ModifiedItem `json:"modified"`
+}
+
+// Empty checks if there are no changes.
+func (cs ChangeSet) Empty() bool {
+	return len(cs.Added) == 0 && len(cs.Removed) == 0 && len(cs.Modified) == 0
+}
+
+// OutputWithChanges is printed when the change set is requested alongside the output.
+type OutputWithChanges struct {
+	Output  Output    `json:"output"`
+	Changes ChangeSet `json:"changes"`
+}
+
+// MergeChanges returns the changes from the output the older changes were compared with to the output the newer
+// ones were, as if both outputs were compared at once. Changes may be read from printed payloads.
+func MergeChanges(older, newer ChangeSet) ChangeSet {
+	olderBefore, olderAfter := older.states()
+	newerBefore, newerAfter := newer.states()
+
+	// Items are taken as they were before the older changes, and as they are after the newer ones.
+	before, after := olderBefore, newerAfter
+	for id, item := range newerBefore {
+		if _, found := before[id]; !found {
+			before[id] = item
+		}
+	}
+	for id, item := range olderAfter {
+		if _, found := after[id]; !found {
+			after[id] = item
+		}
+	}
+
+	var previous, current Output
+	for id := range before {
+		if item := before[id]; item != nil {
+			previous = append(previous, *item)
+		}
+		if item := after[id]; item != nil {
+			current = append(current, *item)
+		}
+	}
+
+	return Diff(previous, current)
+}
+
+// states returns by identity the items changed as they were before the changes and as they are after them,
+// which is nil for the ones missing.
+func (cs ChangeSet) states() (map[string]*DiscoveredItem, map[string]*DiscoveredItem) {
+	before := map[string]*DiscoveredItem{}
+	after := map[string]*DiscoveredItem{}
+
+	for i := range cs.Added {
+		restoreKind(&cs.Added[i])
+		id := identity(cs.Added[i])
+		before[id], after[id] = nil, &cs.Added[i]
+	}
+	for i := range cs.Removed {
+		restoreKind(&cs.Removed[i])
+		id := identity(cs.Removed[i])
+		before[id], after[id] = &cs.Removed[i], nil
+	}
+	for i := range cs.Modified {
+		restoreKind(&cs.Modified[i].Previous)
+		restoreKind(&cs.Modified[i].Current)
+		id := identity(cs.Modified[i].Current)
+		before[id], after[id] = &cs.Modified[i].Previous, &cs.Modified[i].Current
+	}
+
+	return before, after
+}
+
+// content returns the item as printed, ignoring whether it is stale, so items read from a file
+// compare equal to the ones just discovered.
+func content(item DiscoveredItem) string {
+	item.Stale = false
+	data, _ := json.Marshal(item) // nolint: errcheck // items are always marshaled when printed
+	return string(data)
+}
+
+// Diff returns the changes from the previous output to the current one, sorted by item identity.
+func Diff(previous, current Output) ChangeSet {
+	previousItems := make(map[string]DiscoveredItem, len(previous))
+	for _, item := range previous {
+		previousItems[identity(item)] = item
+	}
+
+	changes := ChangeSet{Added: Output{}, Removed: Output{}, Modified: []ModifiedItem{}}
+	currentIDs := make(map[string]bool, len(current))
+	for _, item := range current {
+		id := identity(item)
+		currentIDs[id] = true
+
+		previousItem, found := previousItems[id]
+		switch {
+		case !found:
+			changes.Added = append(changes.Added, item)
+		case content(previousItem) != content(item):
+			changes.Modified = append(changes.Modified, ModifiedItem{Identity: id, Previous: previousItem, Current: item})
+		}
+	}
+
+	for _, item := range previous {
+		if !currentIDs[identity(item)] {
+			changes.Removed = append(changes.Removed, item)
+		}
+	}
+
+	sortByIdentity(changes.Added)
+	sortByIdentity(changes.Removed)
+	sort.SliceStable(changes.Modified, func(i, j int) bool {
+		return changes.Modified[i].Identity < changes.Modified[j].Identity
+	})
+
+	return changes
+}
+
+// LoadState reads the output stored in the changes state file, which is empty if the file does not exist yet.
+func LoadState(file string) (Output, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return Output{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading changes state: %w", err)
+	}
+
+	output := Output{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("parsing changes state: %w", err)
+	}
+	for i := range output {
+		restoreKind(&output[i])
+	}
+
+	return output, nil
+}
+
+// StoreState replaces the output stored in the changes state file.
+func StoreState(file string, output Output) error {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("marshaling changes state: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(file, data, 0o600); err != nil {
+		return fmt.Errorf("storing changes state in %q: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func containerItem(pod, container, img string) DiscoveredItem {
+	return DiscoveredItem{
+		Variables: VariablesMap{
+			cluster:   testServiceClusterName,
+			namespace: "default",
+			podName:   pod,
+			name:      container,
+			image:     img,
+			ports:     kubernetes.PortsMap{"http": 8080},
+		},
+		MetricAnnotations: AnnotationsMap{},
+		kind:              kindPod,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	unchanged := containerItem("nginx", "nginx", "nginx:1.25")
+	removed := containerItem("redis", "redis", "redis:7")
+	before := containerItem("app", "app", "app:1")
+	after := containerItem("app", "app", "app:2")
+	added := containerItem("mysql", "mysql", "mysql:8")
+
+	changes := Diff(Output{unchanged, removed, before}, Output{after, added, unchanged})
+
+	assert.Equal(t, Output{added}, changes.Added)
+	assert.Equal(t, Output{removed}, changes.Removed)
+	require.Len(t, changes.Modified, 1)
+	assert.Equal(t, testServiceClusterName+"/default/pod/app/app", changes.Modified[0].Identity)
+	assert.Equal(t, before, changes.Modified[0].Previous)
+	assert.Equal(t, after, changes.Modified[0].Current)
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	item := containerItem("nginx", "nginx", "nginx:1.25")
+	stale := item
+	stale.Stale = true
+
+	changes := Diff(Output{stale}, Output{item})
+	assert.True(t, changes.Empty(), "Stale items are not modified")
+
+	changes = Diff(Output{}, Output{})
+	assert.True(t, changes.Empty())
+	assert.NotNil(t, changes.Added, "Empty lists are printed instead of null")
+}
+
+func TestMergeChanges(t *testing.T) {
+	unchanged := containerItem("nginx", "nginx", "nginx:1.25")
+	transient := containerItem("job", "job", "job:1")
+	v1 := containerItem("app", "app", "app:1")
+	v2 := containerItem("app", "app", "app:2")
+	v3 := containerItem("app", "app", "app:3")
+	removed := containerItem("redis", "redis", "redis:7")
+	restored := containerItem("mysql", "mysql", "mysql:8")
+
+	first := Output{unchanged, v1, removed, restored}
+	second := Output{unchanged, transient, v2, removed}
+	third := Output{unchanged, v3, restored}
+
+	merged := MergeChanges(Diff(first, second), Diff(second, third))
+	assert.Equal(t, Diff(first, third), merged)
+	assert.Empty(t, merged.Added, "Items added and removed again are not changed")
+	assert.Equal(t, Output{removed}, merged.Removed)
+	require.Len(t, merged.Modified, 1)
+	assert.Equal(t, v1, merged.Modified[0].Previous)
+	assert.Equal(t, v3, merged.Modified[0].Current)
+
+	assert.True(t, MergeChanges(Diff(first, second), Diff(second, first)).Empty(), "Changes reverted are not changed")
+}
+
+func TestState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+
+	previous, err := LoadState(file)
+	require.NoError(t, err, "Missing state is not an error")
+	assert.Empty(t, previous)
+
+	output := Output{containerItem("nginx", "nginx", "nginx:1.25")}
+	require.NoError(t, StoreState(file, output))
+
+	previous, err = LoadState(file)
+	require.NoError(t, err)
+	assert.True(t, Diff(previous, output).Empty(), "Items read from the state compare equal to the ones discovered")
+
+	require.NoError(t, os.WriteFile(file, []byte("{"), 0o600))
+	_, err = LoadState(file)
+	assert.Error(t, err)
+}
@@ -16,16 +16,40 @@ const (
 	id               Property = "id"
 	ip               Property = "ip"
 	ports            Property = "ports"
+	discoveryID      Property = "discoveryID"
+	contentHash      Property = "contentHash"
+	matchedRules     Property = "matchedRules"
+	ruleVariables    Property = "rules"
 
 	// Service-specific properties
-	serviceName      Property = "serviceName"
-	serviceType      Property = "serviceType"
-	clusterIP        Property = "clusterIP"
-	externalIPs      Property = "externalIPs"
-	serviceSelector  Property = "selector"
+	serviceName     Property = "serviceName"
+	serviceType     Property = "serviceType"
+	clusterIP       Property = "clusterIP"
+	externalIPs     Property = "externalIPs"
+	serviceSelector Property = "selector"
+
+	// Custom resource-specific properties
+	resourceAPIVersion Property = "apiVersion"
+	resourceKind       Property = "kind"
+	resourceType       Property = "resource"
+	resourceName       Property = "resourceName"
+
+	// Node-specific properties
+	conditionPrefix  Property = "condition."
+	externalIP       Property = "externalIP"
+	hostname         Property = "hostname"
+	kubeletPort      Property = "kubeletPort"
+	operatingSystem  Property = "os"
+	architecture     Property = "arch"
+	kernelVersion    Property = "kernelVersion"
+	osImage          Property = "osImage"
+	containerRuntime Property = "containerRuntime"
+	kubeletVersion   Property = "kubeletVersion"
 
 	entityRewriteActionReplace Property = "replace"
 	entityRewriteMatch         Property = "${ip}"
 	entityReplaceField         Property = "k8s:${clusterName}:${namespace}:pod:${podName}:${name}"
 	serviceEntityReplaceField  Property = "k8s:${clusterName}:${namespace}:service:${serviceName}"
+	resourceEntityReplaceField Property = "k8s:${clusterName}:${namespace}:%s:${resourceName}"
+	nodeEntityReplaceField     Property = "k8s:${clusterName}:node:${nodeName}"
 )
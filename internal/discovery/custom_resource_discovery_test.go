@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCustomResourceDiscoverer []kubernetes.CustomResourceInfo
+
+func (f fakeCustomResourceDiscoverer) FindCustomResources(_ context.Context, _ []string) ([]kubernetes.CustomResourceInfo, error) {
+	return f, nil
+}
+
+func createCustomResourceInfo(variables map[string]interface{}) kubernetes.CustomResourceInfo {
+	return kubernetes.CustomResourceInfo{
+		APIVersion:  "postgresql.cnpg.io/v1",
+		Kind:        "Cluster",
+		Resource:    "clusters",
+		Name:        "pg",
+		Namespace:   "db",
+		Labels:      kubernetes.LabelsMap{"team": "data"},
+		Annotations: kubernetes.AnnotationsMap{"owner": "dba"},
+		Variables:   variables,
+		Cluster:     testServiceClusterName,
+	}
+}
+
+func TestProcessCustomResources(t *testing.T) {
+	output := processCustomResources([]kubernetes.CustomResourceInfo{
+		createCustomResourceInfo(map[string]interface{}{
+			"primary":   "pg-rw",
+			namespace:   "overridden",
+			"instances": int64(3),
+		}),
+	})
+
+	require.Len(t, output, 1)
+	item := output[0]
+
+	assert.Equal(t, testServiceClusterName, item.Variables[cluster])
+	assert.Equal(t, "db", item.Variables[namespace], "JSONPath variables do not override common ones")
+	assert.Equal(t, "postgresql.cnpg.io/v1", item.Variables[resourceAPIVersion])
+	assert.Equal(t, "Cluster", item.Variables[resourceKind])
+	assert.Equal(t, "clusters", item.Variables[resourceType])
+	assert.Equal(t, "pg", item.Variables[resourceName])
+	assert.Equal(t, "pg-rw", item.Variables["primary"])
+	assert.Equal(t, int64(3), item.Variables["instances"])
+	assert.Equal(t, "data", item.Variables[labelPrefix+"team"])
+	assert.Equal(t, "dba", item.Variables[annotationPrefix+"owner"])
+
+	assert.NotContains(t, item.MetricAnnotations, annotationPrefix+"owner")
+	assert.Empty(t, item.EntityRewrites, "No rewrites without an ip variable")
+}
+
+func TestProcessCustomResources_EntityRewrites(t *testing.T) {
+	output := processCustomResources([]kubernetes.CustomResourceInfo{
+		createCustomResourceInfo(map[string]interface{}{ip: "10.0.0.1"}),
+	})
+
+	require.Len(t, output, 1)
+	require.Len(t, output[0].EntityRewrites, 1)
+
+	rewrite := output[0].EntityRewrites[0]
+	assert.Equal(t, entityRewriteActionReplace, rewrite.Action)
+	assert.Equal(t, entityRewriteMatch, rewrite.Match)
+	assert.Equal(t, "k8s:${clusterName}:${namespace}:cluster:${resourceName}", rewrite.ReplaceField)
+	assert.NotContains(t, output[0].MetricAnnotations, ip)
+}
+
+func TestDiscoverer_RunCustomResources(t *testing.T) {
+	d := &Discoverer{
+		kubelet: fakeKubeletClient(t),
+	}
+	d.SetCustomResourceDiscoverer(fakeCustomResourceDiscoverer{createCustomResourceInfo(nil)})
+
+	output, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	assert.Equal(t, "pg", output[0].Variables[resourceName], "Only custom resources are discovered")
+}
@@ -0,0 +1,114 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Customizations select the items discovered and the annotations kept in them, and replace the entity names
+// they are rewritten to, as set in the configuration file.
+type Customizations struct {
+	selector           labels.Selector
+	includeAnnotations []*regexp.Regexp
+	excludeAnnotations []*regexp.Regexp
+	rewrites           map[string]string
+}
+
+// NewCustomizations parses the customizations configured, which are nil if there are none.
+func NewCustomizations(c *config.Config) (*Customizations, error) {
+	rewrites := map[string]string{}
+	for kind, template := range map[string]string{
+		kindPod:            c.EntityRewrites.Pod,
+		kindService:        c.EntityRewrites.Service,
+		kindNode:           c.EntityRewrites.Node,
+		kindCustomResource: c.EntityRewrites.CustomResource,
+	} {
+		if template != "" {
+			rewrites[kind] = template
+		}
+	}
+
+	if c.Selectors.Labels == "" && len(c.AnnotationFilter.Include) == 0 && len(c.AnnotationFilter.Exclude) == 0 && len(rewrites) == 0 {
+		return nil, nil
+	}
+
+	cz := &Customizations{rewrites: rewrites}
+
+	if c.Selectors.Labels != "" {
+		selector, err := labels.Parse(c.Selectors.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("parsing label selector: %w", err)
+		}
+		cz.selector = selector
+	}
+
+	var err error
+	if cz.includeAnnotations, err = compileAll(c.AnnotationFilter.Include); err != nil {
+		return nil, fmt.Errorf("parsing included annotations: %w", err)
+	}
+	if cz.excludeAnnotations, err = compileAll(c.AnnotationFilter.Exclude); err != nil {
+		return nil, fmt.Errorf("parsing excluded annotations: %w", err)
+	}
+
+	return cz, nil
+}
+
+// apply drops the items not matching the selectors and the annotations filtered out from the rest,
+// replacing the entity names they are rewritten to.
+func (cz *Customizations) apply(output Output) Output {
+	result := make(Output, 0, len(output))
+
+	for _, item := range output {
+		if cz.selector != nil && !cz.selector.Matches(itemLabels(item.Variables)) {
+			continue
+		}
+
+		for k := range item.Variables {
+			if annotation, isAnnotation := strings.CutPrefix(k, annotationPrefix); isAnnotation && !cz.keepAnnotation(annotation) {
+				delete(item.Variables, k)
+			}
+		}
+
+		if template, found := cz.rewrites[item.kind]; found {
+			for i := range item.EntityRewrites {
+				item.EntityRewrites[i].ReplaceField = template
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+func (cz *Customizations) keepAnnotation(annotation string) bool {
+	if len(cz.includeAnnotations) > 0 && !matchesAny(cz.includeAnnotations, annotation) {
+		return false
+	}
+	return !matchesAny(cz.excludeAnnotations, annotation)
+}
+
+func compileAll(expressions []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(expressions))
+	for _, expression := range expressions {
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func matchesAny(expressions []*regexp.Regexp, value string) bool {
+	for _, re := range expressions {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCustomizations(t *testing.T) {
+	cz, err := NewCustomizations(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, cz, "Nothing is customized unless configured")
+
+	_, err = NewCustomizations(&config.Config{Selectors: config.Selectors{Labels: "app in (shop"}})
+	assert.Error(t, err)
+
+	_, err = NewCustomizations(&config.Config{AnnotationFilter: config.AnnotationFilter{Include: []string{"["}}})
+	assert.Error(t, err)
+}
+
+func TestCustomizations_Apply(t *testing.T) {
+	cz, err := NewCustomizations(&config.Config{
+		Selectors: config.Selectors{Labels: "app.kubernetes.io/part-of=shop"},
+		AnnotationFilter: config.AnnotationFilter{
+			Include: []string{`^prometheus\.io/`, "^team$"},
+			Exclude: []string{"^prometheus.io/path$"},
+		},
+		EntityRewrites: config.EntityRewriteTemplates{Service: "k8s:${clusterName}:svc:${serviceName}"},
+	})
+	require.NoError(t, err)
+
+	pod := containerItem("cart", "cart", "cart:1")
+	pod.Variables[labelPrefix+"app.kubernetes.io/part-of"] = "shop"
+	pod.Variables[annotationPrefix+"prometheus.io/scrape"] = "true"
+	pod.Variables[annotationPrefix+"prometheus.io/path"] = "/metrics"
+	pod.Variables[annotationPrefix+"team"] = "caos"
+	pod.Variables[annotationPrefix+"kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+	pod.EntityRewrites = getReplacements()
+
+	service := DiscoveredItem{
+		Variables:      VariablesMap{serviceName: "cart", labelPrefix + "app.kubernetes.io/part-of": "shop"},
+		EntityRewrites: []Replacement{{Action: entityRewriteActionReplace, Match: entityRewriteMatch, ReplaceField: serviceEntityReplaceField}},
+		kind:           kindService,
+	}
+
+	other := containerItem("blog", "blog", "blog:1")
+
+	output := cz.apply(Output{pod, service, other})
+	require.Len(t, output, 2, "Items not matching the label selector are dropped")
+
+	assert.Equal(t, "true", output[0].Variables[annotationPrefix+"prometheus.io/scrape"])
+	assert.Equal(t, "caos", output[0].Variables[annotationPrefix+"team"])
+	assert.NotContains(t, output[0].Variables, annotationPrefix+"prometheus.io/path", "Excluded annotations are dropped even if included")
+	assert.NotContains(t, output[0].Variables, annotationPrefix+"kubectl.kubernetes.io/last-applied-configuration", "Only included annotations are kept")
+	assert.Equal(t, entityReplaceField, output[0].EntityRewrites[0].ReplaceField, "Kinds without template keep the default one")
+
+	assert.Equal(t, "k8s:${clusterName}:svc:${serviceName}", output[1].EntityRewrites[0].ReplaceField)
+}
+
+func TestDiscoverer_RunWithCustomizations(t *testing.T) {
+	kubelet := fakePartialKubelet{containers: []kubernetes.ContainerInfo{
+		{Name: "cart", PodName: "cart", Namespace: "default", Image: "cart:1", PodLabels: map[string]string{"tier": "backend"}},
+		{Name: "web", PodName: "web", Namespace: "default", Image: "web:1", PodLabels: map[string]string{"tier": "frontend"}},
+	}}
+
+	cz, err := NewCustomizations(&config.Config{Selectors: config.Selectors{Labels: "tier=backend"}})
+	require.NoError(t, err)
+	rules, err := NewRules(&config.Config{Rules: []config.Rule{
+		{Name: "any", Integration: "nri-flex", Match: config.RuleMatch{LabelSelector: "tier"}},
+	}})
+	require.NoError(t, err)
+
+	d := NewDiscoverer(nil, kubelet, false)
+	d.SetCustomizations(cz)
+	d.SetRules(rules)
+
+	output, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	assert.Equal(t, "cart", output[0].Variables[podName])
+	assert.Equal(t, "any", output[0].Variables[matchedRules])
+}
@@ -1,6 +1,8 @@
 package discovery
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -27,17 +29,66 @@ type DiscoveredItem struct {
 	Variables         VariablesMap   `json:"variables"`
 	MetricAnnotations AnnotationsMap `json:"metricAnnotations"`
 	EntityRewrites    []Replacement  `json:"entityRewrites"`
+	// Stale items are served from the output cache since discovery failed.
+	Stale bool `json:"stale,omitempty"`
+
+	// kind of object the item was discovered from, which is not printed.
+	kind string
+	// Pod details only rendered as OpenTelemetry k8s_observer endpoints, kept out of the variables.
+	podUID        string
+	portProtocols kubernetes.ProtocolsMap
 }
 
 // Output defines the final output of the discovery executable.
 type Output []DiscoveredItem
 
+// Sources of discovered items, used to report which part of the discovery failed.
+const (
+	SourcePods            = "pods"
+	SourceServices        = "services"
+	SourceCustomResources = "customResources"
+	SourceNodes           = "nodes"
+)
+
+// SourceError is a failure found discovering part of a source, e.g. the services of a namespace.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("discovering %s: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// PartialOutputError is returned by Run along with the items that could be discovered when part of the
+// discovery failed, unless the discoverer is strict.
+type PartialOutputError struct {
+	Errors []*SourceError
+}
+
+func (e *PartialOutputError) Error() string {
+	errs := make([]error, 0, len(e.Errors))
+	for _, sourceErr := range e.Errors {
+		errs = append(errs, sourceErr)
+	}
+	return errors.Join(errs...).Error()
+}
+
 // Discoverer implements the specific discovery mechanism.
 type Discoverer struct {
-	namespaces        []string
-	kubelet           kubernetes.Kubelet
-	serviceDiscoverer kubernetes.ServiceDiscoverer
-	discoverServices  bool
+	namespaces               []string
+	kubelet                  kubernetes.Kubelet
+	serviceDiscoverer        kubernetes.ServiceDiscoverer
+	customResourceDiscoverer kubernetes.CustomResourceDiscoverer
+	nodeDiscoverer           kubernetes.NodeDiscoverer
+	discoverServices         bool
+	strict                   bool
+	rules                    *Rules
+	customizations           *Customizations
 }
 
 // NewDiscoverer creates a new discoverer implementation (containers only by default).
@@ -54,30 +105,80 @@ func (d *Discoverer) SetServiceDiscoverer(sd kubernetes.ServiceDiscoverer) {
 	d.serviceDiscoverer = sd
 }
 
-// Run executes the discovery mechanism.
-func (d *Discoverer) Run() (Output, error) {
-	output := Output{}
+// SetCustomResourceDiscoverer sets the custom resource discoverer, making Run discover custom resources instead of containers.
+func (d *Discoverer) SetCustomResourceDiscoverer(crd kubernetes.CustomResourceDiscoverer) {
+	d.customResourceDiscoverer = crd
+}
 
-	if !d.discoverServices {
-		// Default: discover containers only
-		pods, err := d.kubelet.FindContainers(d.namespaces)
-		if err != nil {
-			return nil, err
-		}
-		output = append(output, processContainers(pods)...)
-	} else {
+// SetNodeDiscoverer sets the node discoverer, making Run discover nodes instead of containers.
+func (d *Discoverer) SetNodeDiscoverer(nd kubernetes.NodeDiscoverer) {
+	d.nodeDiscoverer = nd
+}
+
+// SetStrict makes Run fail when any part of the discovery fails, instead of returning a PartialOutputError
+// along with the items that could be discovered.
+func (d *Discoverer) SetStrict(strict bool) {
+	d.strict = strict
+}
+
+// SetRules sets the rules the discovered items are matched against.
+func (d *Discoverer) SetRules(rules *Rules) {
+	d.rules = rules
+}
+
+// SetCustomizations sets the customizations applied to the discovered items before matching them against the rules.
+func (d *Discoverer) SetCustomizations(cz *Customizations) {
+	d.customizations = cz
+}
+
+// Run executes the discovery mechanism, stopping the requests in flight as soon as the context is done.
+// When only part of the discovery fails, the items discovered are returned along with a PartialOutputError.
+func (d *Discoverer) Run(ctx context.Context) (Output, error) {
+	source, output, err := d.discover(ctx)
+	if d.customizations != nil {
+		output = d.customizations.apply(output)
+	}
+	if d.rules != nil {
+		output = d.rules.apply(output)
+	}
+	identify(output)
+	if err == nil {
+		return output, nil
+	}
+
+	var partialErr *kubernetes.PartialResultError
+	if d.strict || !errors.As(err, &partialErr) {
+		return nil, err
+	}
+
+	result := &PartialOutputError{}
+	for _, sourceErr := range partialErr.Errs {
+		result.Errors = append(result.Errors, &SourceError{Source: source, Err: sourceErr})
+	}
+	return output, result
+}
+
+// discover returns the items found in the source configured, which are partial if the error is a kubernetes.PartialResultError.
+func (d *Discoverer) discover(ctx context.Context) (string, Output, error) {
+	switch {
+	case d.discoverServices:
 		// Discover services instead of containers
 		if d.serviceDiscoverer == nil {
-			return nil, fmt.Errorf("service discoverer not configured but discover-services flag is set")
+			return SourceServices, nil, fmt.Errorf("service discoverer not configured but discover-services flag is set")
 		}
-		services, err := d.serviceDiscoverer.FindServices(d.namespaces)
-		if err != nil {
-			return nil, err
-		}
-		output = append(output, processServices(services)...)
+		services, err := d.serviceDiscoverer.FindServices(ctx, d.namespaces)
+		return SourceServices, processServices(services), err
+	case d.customResourceDiscoverer != nil:
+		resources, err := d.customResourceDiscoverer.FindCustomResources(ctx, d.namespaces)
+		return SourceCustomResources, processCustomResources(resources), err
+	case d.nodeDiscoverer != nil:
+		nodes, err := d.nodeDiscoverer.FindNodes(ctx)
+		return SourceNodes, processNodes(nodes), err
+	default:
+		// Default: discover containers only
+		pods, err := d.kubelet.FindContainers(ctx, d.namespaces)
+		return SourcePods, processContainers(pods), err
 	}
-
-	return output, nil
 }
 
 func processContainers(containers []kubernetes.ContainerInfo) Output {
@@ -112,6 +213,9 @@ func processContainers(containers []kubernetes.ContainerInfo) Output {
 			Variables:         discoveredProperties,
 			MetricAnnotations: metricAnnotations,
 			EntityRewrites:    getReplacements(),
+			kind:              kindPod,
+			podUID:            c.PodUID,
+			portProtocols:     c.PortProtocols,
 		}
 		output = append(output, item)
 	}
@@ -130,7 +234,7 @@ func getReplacements() []Replacement {
 }
 
 var annotationExclusions = []string{
-	id, ip, nodeIP, ports,
+	id, ip, nodeIP, ports, externalIP, kubeletPort,
 }
 
 func filterAnnotations(props VariablesMap) AnnotationsMap {
@@ -195,6 +299,118 @@ func processServices(services []kubernetes.ServiceInfo) Output {
 					ReplaceField: serviceEntityReplaceField,
 				},
 			},
+			kind: kindService,
+		}
+		output = append(output, item)
+	}
+
+	return output
+}
+
+func processCustomResources(resources []kubernetes.CustomResourceInfo) Output {
+	// default empty, instead of nil.
+	output := Output{}
+	for _, cr := range resources {
+		// new map for each resource.
+		discoveredProperties := make(VariablesMap)
+
+		// variables extracted through JSONPath never override the common ones set below.
+		for k, v := range cr.Variables {
+			discoveredProperties[k] = v
+		}
+
+		discoveredProperties[cluster] = cr.Cluster
+		discoveredProperties[namespace] = cr.Namespace
+		discoveredProperties[resourceAPIVersion] = cr.APIVersion
+		discoveredProperties[resourceKind] = cr.Kind
+		discoveredProperties[resourceType] = cr.Resource
+		discoveredProperties[resourceName] = cr.Name
+
+		for k, v := range cr.Labels {
+			discoveredProperties[labelPrefix+k] = v
+		}
+
+		for k, v := range cr.Annotations {
+			discoveredProperties[annotationPrefix+k] = v
+		}
+
+		// remove from discovered properties, k8s annotations
+		metricAnnotations := filterAnnotations(discoveredProperties)
+
+		// Entity names can only be rewritten when the resource exposes an ip through its variables.
+		rewrites := []Replacement{}
+		if _, ok := discoveredProperties[ip]; ok {
+			rewrites = append(rewrites, Replacement{
+				Action:       entityRewriteActionReplace,
+				Match:        entityRewriteMatch,
+				ReplaceField: fmt.Sprintf(resourceEntityReplaceField, strings.ToLower(cr.Kind)),
+			})
+		}
+
+		item := DiscoveredItem{
+			Variables:         discoveredProperties,
+			MetricAnnotations: metricAnnotations,
+			EntityRewrites:    rewrites,
+			kind:              kindCustomResource,
+		}
+		output = append(output, item)
+	}
+
+	return output
+}
+
+func processNodes(nodes []kubernetes.NodeInfo) Output {
+	// default empty, instead of nil.
+	output := Output{}
+	for _, n := range nodes {
+		// new map for each node.
+		discoveredProperties := make(VariablesMap)
+
+		discoveredProperties[cluster] = n.Cluster
+		discoveredProperties[node] = n.Name
+		// the internal address is exposed as ip too, so integrations can be configured as for pods.
+		discoveredProperties[ip] = n.InternalIP
+		discoveredProperties[nodeIP] = n.InternalIP
+		if n.ExternalIP != "" {
+			discoveredProperties[externalIP] = n.ExternalIP
+		}
+		if n.Hostname != "" {
+			discoveredProperties[hostname] = n.Hostname
+		}
+		discoveredProperties[kubeletPort] = n.KubeletPort
+		discoveredProperties[operatingSystem] = n.OperatingSystem
+		discoveredProperties[architecture] = n.Architecture
+		discoveredProperties[kernelVersion] = n.KernelVersion
+		discoveredProperties[osImage] = n.OSImage
+		discoveredProperties[containerRuntime] = n.ContainerRuntimeVersion
+		discoveredProperties[kubeletVersion] = n.KubeletVersion
+
+		for k, v := range n.Conditions {
+			discoveredProperties[conditionPrefix+k] = v
+		}
+
+		for k, v := range n.Labels {
+			discoveredProperties[labelPrefix+k] = v
+		}
+
+		for k, v := range n.Annotations {
+			discoveredProperties[annotationPrefix+k] = v
+		}
+
+		// remove from discovered properties, k8s annotations
+		metricAnnotations := filterAnnotations(discoveredProperties)
+
+		item := DiscoveredItem{
+			Variables:         discoveredProperties,
+			MetricAnnotations: metricAnnotations,
+			EntityRewrites: []Replacement{
+				{
+					Action:       entityRewriteActionReplace,
+					Match:        entityRewriteMatch,
+					ReplaceField: nodeEntityReplaceField,
+				},
+			},
+			kind: kindNode,
 		}
 		output = append(output, item)
 	}
@@ -1,7 +1,9 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -65,7 +67,7 @@ func TestDiscoverer_Run(t *testing.T) {
 				namespaces: tt.fields.namespaces,
 				kubelet:    fakeKubeletClient(t),
 			}
-			got, err := d.Run()
+			got, err := d.Run(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -75,12 +77,60 @@ func TestDiscoverer_Run(t *testing.T) {
 	}
 }
 
+type fakePartialKubelet struct {
+	containers []kubernetes.ContainerInfo
+	err        error
+}
+
+func (f fakePartialKubelet) FindContainers(_ context.Context, _ []string) ([]kubernetes.ContainerInfo, error) {
+	return f.containers, f.err
+}
+
+func TestDiscoverer_RunPartialResult(t *testing.T) {
+	nsErr := errors.New("namespace forbidden")
+	kubelet := fakePartialKubelet{
+		containers: []kubernetes.ContainerInfo{{Name: "nginx", PodName: "nginx", Namespace: "default"}},
+		err:        &kubernetes.PartialResultError{Errs: []error{nsErr}},
+	}
+
+	t.Run("best_effort", func(t *testing.T) {
+		d := NewDiscoverer(nil, kubelet, false)
+
+		output, err := d.Run(context.Background())
+		var partialErr *PartialOutputError
+		require.ErrorAs(t, err, &partialErr)
+		require.Len(t, partialErr.Errors, 1)
+		assert.Equal(t, SourcePods, partialErr.Errors[0].Source)
+		assert.ErrorIs(t, partialErr.Errors[0], nsErr)
+		require.Len(t, output, 1, "Items discovered are returned along with the error")
+		assert.Equal(t, "nginx", output[0].Variables[podName])
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		d := NewDiscoverer(nil, kubelet, false)
+		d.SetStrict(true)
+
+		output, err := d.Run(context.Background())
+		assert.ErrorIs(t, err, nsErr)
+		assert.Nil(t, output)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		d := NewDiscoverer(nil, fakePartialKubelet{err: nsErr}, false)
+
+		output, err := d.Run(context.Background())
+		assert.ErrorIs(t, err, nsErr)
+		assert.False(t, errors.As(err, new(*PartialOutputError)), "Nothing could be discovered")
+		assert.Nil(t, output)
+	})
+}
+
 func Test_PodsWithMultiplePorts_ReturnsIndexAndName(t *testing.T) {
 	d := &Discoverer{
 		namespaces: []string{"test"},
 		kubelet:    fakeKubeletClient(t),
 	}
-	result, err := d.Run()
+	result, err := d.Run(context.Background())
 	require.NoError(t, err)
 
 	require.Len(t, result, 1)
@@ -109,6 +159,7 @@ func fakeKubeletClient(t *testing.T) kubernetes.Kubelet {
 
 	k8sClient, cf, inClusterConfig, logger := getTestData(server)
 	httpClient, _ := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(5),
 		internalhttp.WithLogger(logger),
@@ -165,6 +216,7 @@ func fakePodList() corev1.PodList {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test",
 			Namespace: "test",
+			UID:       "test-uid",
 			Labels: map[string]string{
 				"team": "caos",
 			},
@@ -186,6 +238,7 @@ func fakePodList() corev1.PodList {
 						{
 							Name:          "third",
 							ContainerPort: 3,
+							Protocol:      corev1.ProtocolUDP,
 						},
 					},
 				},
@@ -213,6 +266,7 @@ func fakePodList() corev1.PodList {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "fake",
 			Namespace: "fake",
+			UID:       "fake-uid",
 			Labels: map[string]string{
 				"team": "caos",
 			},
@@ -284,6 +338,8 @@ func items() map[string]DiscoveredItem {
 				image:                     "testImage",
 				labelPrefix + "team":      "caos",
 				annotationPrefix + "test": "test",
+				discoveryID:               "21aa52705473b2e5",
+				contentHash:               "61489160d97937c6",
 			},
 			MetricAnnotations: AnnotationsMap{
 				cluster:              "",
@@ -301,6 +357,9 @@ func items() map[string]DiscoveredItem {
 					ReplaceField: "k8s:${clusterName}:${namespace}:pod:${podName}:${name}",
 				},
 			},
+			kind:          kindPod,
+			podUID:        "test-uid",
+			portProtocols: kubernetes.ProtocolsMap{"0": "TCP", "1": "TCP", "2": "UDP", "first": "TCP", "third": "UDP"},
 		},
 		"fake": {
 			Variables: VariablesMap{
@@ -316,6 +375,8 @@ func items() map[string]DiscoveredItem {
 				image:                     "fakeImage",
 				labelPrefix + "team":      "caos",
 				annotationPrefix + "fake": "fake",
+				discoveryID:               "5c49d3ed77ed3930",
+				contentHash:               "92b50a58254ce960",
 			},
 			MetricAnnotations: AnnotationsMap{
 				cluster:              "",
@@ -333,6 +394,9 @@ func items() map[string]DiscoveredItem {
 					ReplaceField: "k8s:${clusterName}:${namespace}:pod:${podName}:${name}",
 				},
 			},
+			kind:          kindPod,
+			podUID:        "fake-uid",
+			portProtocols: kubernetes.ProtocolsMap{"0": "TCP"},
 		},
 	}
 
@@ -344,9 +408,10 @@ func allItems() Output {
 	test := discoveredItems["test"]
 	fake := discoveredItems["fake"]
 
+	// sorted by identity
 	output := Output{
-		test,
 		fake,
+		test,
 	}
 	return output
 }
@@ -0,0 +1,111 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"sigs.k8s.io/yaml"
+)
+
+// tableEmptyCell is printed in the table cells of the values an item does not have, e.g. the pod of a service.
+const tableEmptyCell = "-"
+
+// Renderer renders the output in the format configured.
+type Renderer struct {
+	format             string
+	prometheusPortName string
+}
+
+// NewRenderer creates a Renderer for the output format configured.
+func NewRenderer(c *config.Config) *Renderer {
+	return &Renderer{
+		format:             c.OutputFormat,
+		prometheusPortName: c.PrometheusPortName,
+	}
+}
+
+// Render returns the output in the format configured.
+func (r *Renderer) Render(output Output) ([]byte, error) {
+	switch r.format {
+	case config.OutputFormatJSON, config.OutputFormatJSONPretty, config.OutputFormatYAML:
+		return r.Marshal(output)
+	case config.OutputFormatNDJSON:
+		return ndjson(output)
+	case config.OutputFormatTable:
+		return table(output), nil
+	case config.OutputFormatPrometheusFileSD:
+		return json.Marshal(prometheusTargetGroups(output, r.prometheusPortName))
+	case config.OutputFormatOTelK8sObserver:
+		return json.Marshal(otelEndpoints(output))
+	}
+
+	return nil, fmt.Errorf("%w: %q", config.ErrInvalidOutputFormat, r.format)
+}
+
+// Marshal returns any value, e.g. the changes from the previous output, in the JSON or YAML format configured.
+func (r *Renderer) Marshal(v interface{}) ([]byte, error) {
+	switch r.format {
+	case config.OutputFormatJSON:
+		return json.Marshal(v)
+	case config.OutputFormatJSONPretty:
+		return json.MarshalIndent(v, "", "  ")
+	case config.OutputFormatYAML:
+		data, err := yaml.Marshal(v)
+		// Output is printed followed by a new line.
+		return bytes.TrimSuffix(data, []byte("\n")), err
+	}
+
+	return nil, fmt.Errorf("%w: %q", config.ErrChangesRequireJSON, r.format)
+}
+
+// ndjson returns each item as JSON in its own line.
+func ndjson(output Output) ([]byte, error) {
+	lines := make([][]byte, 0, len(output))
+	for _, item := range output {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// table returns the namespace, pod, container, address and ports of each item aligned in columns.
+func table(output Output) []byte {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "NAMESPACE\tPOD\tCONTAINER\tIP\tPORTS") // nolint: errcheck // writes to a buffer
+	for _, item := range output {
+		v := item.Variables
+		var portsCell []string
+		for _, port := range containerPorts(itemPorts(item)) {
+			cell := strconv.Itoa(port.number)
+			if port.named() {
+				cell = port.name + ":" + cell
+			}
+			portsCell = append(portsCell, cell)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", // nolint: errcheck // writes to a buffer
+			tableCell(v[namespace]), tableCell(v[podName]), tableCell(v[name]),
+			tableCell(itemHost(item)), tableCell(strings.Join(portsCell, ",")),
+		)
+	}
+	w.Flush() // nolint: errcheck // writes to a buffer
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func tableCell(value interface{}) string {
+	if value == nil || value == "" {
+		return tableEmptyCell
+	}
+	return fmt.Sprint(value)
+}
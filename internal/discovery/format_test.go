@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRenderer_JSON(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatJSON})
+
+	output := Output{containerItem("nginx", "nginx", "nginx:1.25")}
+	data, err := r.Render(output)
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(output)
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+}
+
+func TestRenderer_JSONPretty(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatJSONPretty})
+
+	output := Output{containerItem("nginx", "nginx", "nginx:1.25")}
+	data, err := r.Render(output)
+	require.NoError(t, err)
+
+	expected, err := json.MarshalIndent(output, "", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+}
+
+func TestRenderer_NDJSON(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatNDJSON})
+
+	output := Output{containerItem("nginx", "nginx", "nginx:1.25"), containerItem("redis", "redis", "redis:7")}
+	data, err := r.Render(output)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(data), "\n")
+	require.Len(t, lines, 2, "Each item is printed in its own line")
+	for i, line := range lines {
+		var item DiscoveredItem
+		require.NoError(t, json.Unmarshal([]byte(line), &item))
+		assert.Equal(t, output[i].Variables[podName], item.Variables[podName])
+	}
+
+	data, err = r.Render(Output{})
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestRenderer_YAML(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatYAML})
+
+	data, err := r.Render(Output{containerItem("nginx", "nginx", "nginx:1.25")})
+	require.NoError(t, err)
+	assert.False(t, strings.HasSuffix(string(data), "\n"), "The new line is added when printed")
+
+	var output Output
+	require.NoError(t, yaml.Unmarshal(data, &output))
+	require.Len(t, output, 1)
+	assert.Equal(t, "nginx:1.25", output[0].Variables[image])
+}
+
+func TestRenderer_Table(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatTable})
+
+	nginx := containerItem("nginx", "nginx", "nginx:1.25")
+	nginx.Variables[ip] = "10.1.0.1"
+	nginx.Variables[ports] = kubernetes.PortsMap{"0": 8080, "http": 8080, "1": 9113}
+	service := DiscoveredItem{Variables: VariablesMap{namespace: "default", serviceName: "redis", clusterIP: "10.96.0.1"}}
+
+	data, err := r.Render(Output{nginx, service})
+	require.NoError(t, err)
+
+	expected := "" +
+		"NAMESPACE  POD    CONTAINER  IP         PORTS\n" +
+		"default    nginx  nginx      10.1.0.1   http:8080,9113\n" +
+		"default    -      -          10.96.0.1  -"
+	assert.Equal(t, expected, string(data))
+}
+
+func TestRenderer_Marshal(t *testing.T) {
+	changes := ChangeSet{Added: Output{}, Removed: Output{}, Modified: []ModifiedItem{}}
+
+	for _, format := range []string{config.OutputFormatJSON, config.OutputFormatJSONPretty, config.OutputFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := NewRenderer(&config.Config{OutputFormat: format}).Marshal(changes)
+			require.NoError(t, err)
+
+			var read ChangeSet
+			require.NoError(t, yaml.Unmarshal(data, &read))
+			assert.Equal(t, changes, read)
+		})
+	}
+
+	_, err := NewRenderer(&config.Config{OutputFormat: config.OutputFormatTable}).Marshal(changes)
+	assert.ErrorIs(t, err, config.ErrChangesRequireJSON)
+}
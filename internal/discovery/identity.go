@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hashLength is the number of hex characters kept from the hashes added to the items.
+const hashLength = 16
+
+// Kinds of discovered items.
+const (
+	kindPod            = "pod"
+	kindService        = "service"
+	kindNode           = "node"
+	kindCustomResource = "customResource"
+)
+
+// kindFromVariables returns the kind of object an item read from printed output was discovered from, as the kind
+// is not printed.
+func kindFromVariables(v VariablesMap) string {
+	switch {
+	case v[resourceName] != nil:
+		return kindCustomResource
+	case v[serviceName] != nil:
+		return kindService
+	case v[podName] != nil:
+		return kindPod
+	default:
+		return kindNode
+	}
+}
+
+// identity returns the key identifying the item across runs, built from the cluster and namespace plus
+// the pod and container, the service, the custom resource or the node the item was discovered from.
+func identity(item DiscoveredItem) string {
+	v := item.Variables
+	parts := []string{fmt.Sprint(v[cluster])}
+
+	switch item.kind {
+	case kindCustomResource:
+		parts = append(parts, fmt.Sprint(v[namespace]), fmt.Sprint(v[resourceAPIVersion]), fmt.Sprint(v[resourceType]), fmt.Sprint(v[resourceName]))
+	case kindService:
+		parts = append(parts, fmt.Sprint(v[namespace]), "service", fmt.Sprint(v[serviceName]))
+	case kindPod:
+		parts = append(parts, fmt.Sprint(v[namespace]), "pod", fmt.Sprint(v[podName]), fmt.Sprint(v[name]))
+	default:
+		parts = append(parts, "node", fmt.Sprint(v[node]))
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// restoreKind sets the kind of an item read from printed output.
+func restoreKind(item *DiscoveredItem) {
+	if item.kind == "" {
+		item.kind = kindFromVariables(item.Variables)
+	}
+}
+
+func sortByIdentity(output Output) {
+	sort.SliceStable(output, func(i, j int) bool {
+		return identity(output[i]) < identity(output[j])
+	})
+}
+
+func shortHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// identify sorts the items by identity, so the same state is always printed the same way, and adds to each
+// of them the discoveryID variable, hashing its identity, and the contentHash one, hashing the rest of variables.
+func identify(output Output) {
+	sortByIdentity(output)
+
+	for _, item := range output {
+		item.Variables[discoveryID] = shortHash([]byte(identity(item)))
+
+		delete(item.Variables, contentHash)
+		// Maps are marshaled with sorted keys, so equal variables always produce the same hash.
+		data, _ := json.Marshal(item.Variables) // nolint: errcheck // variables are always marshaled when printed
+		item.Variables[contentHash] = shortHash(data)
+	}
+}
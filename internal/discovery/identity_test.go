@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      string
+		variables VariablesMap
+		want      string
+	}{
+		{
+			name:      "container",
+			kind:      kindPod,
+			variables: VariablesMap{cluster: "c", namespace: "ns", podName: "pod", name: "app", node: "node"},
+			want:      "c/ns/pod/pod/app",
+		},
+		{
+			name:      "service",
+			kind:      kindService,
+			variables: VariablesMap{cluster: "c", namespace: "ns", serviceName: "svc"},
+			want:      "c/ns/service/svc",
+		},
+		{
+			name:      "custom_resource",
+			kind:      kindCustomResource,
+			variables: VariablesMap{cluster: "c", namespace: "ns", resourceAPIVersion: "kafka.strimzi.io/v1beta2", resourceType: "kafkas", resourceName: "kafka"},
+			want:      "c/ns/kafka.strimzi.io/v1beta2/kafkas/kafka",
+		},
+		{
+			name:      "node",
+			kind:      kindNode,
+			variables: VariablesMap{cluster: "c", node: "node"},
+			want:      "c/node/node",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.kind, kindFromVariables(tt.variables), "The kind of items read from printed output is restored")
+			assert.Equal(t, tt.want, identity(DiscoveredItem{Variables: tt.variables, kind: tt.kind}))
+		})
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	first := func() Output {
+		return Output{
+			containerItem("redis", "redis", "redis:7"),
+			containerItem("app", "sidecar", "envoy:1"),
+			containerItem("app", "app", "app:1"),
+		}
+	}
+	second := func() Output {
+		output := first()
+		return Output{output[2], output[0], output[1]}
+	}
+
+	a, b := first(), second()
+	identify(a)
+	identify(b)
+
+	for i, pod := range []string{"app", "app", "redis"} {
+		assert.Equal(t, pod, a[i].Variables[podName], "Items are sorted by identity")
+	}
+	assert.Equal(t, "app", a[0].Variables[name])
+
+	aJSON, err := json.Marshal(a)
+	require.NoError(t, err)
+	bJSON, err := json.Marshal(b)
+	require.NoError(t, err)
+	assert.Equal(t, string(aJSON), string(bJSON), "The same items are printed the same way regardless of the order found")
+
+	for _, item := range a {
+		assert.Len(t, item.Variables[discoveryID], hashLength)
+		assert.Len(t, item.Variables[contentHash], hashLength)
+	}
+	assert.NotEqual(t, a[0].Variables[discoveryID], a[1].Variables[discoveryID])
+}
+
+func TestIdentify_ContentHash(t *testing.T) {
+	before := Output{containerItem("app", "app", "app:1")}
+	after := Output{containerItem("app", "app", "app:2")}
+	identify(before)
+	identify(after)
+
+	assert.Equal(t, before[0].Variables[discoveryID], after[0].Variables[discoveryID], "Identity does not depend on content")
+	assert.NotEqual(t, before[0].Variables[contentHash], after[0].Variables[contentHash])
+
+	// Identifying items again, e.g. read from a file, keeps the same hashes.
+	hash := before[0].Variables[contentHash]
+	identify(before)
+	assert.Equal(t, hash, before[0].Variables[contentHash])
+}
@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNodeDiscoverer []kubernetes.NodeInfo
+
+func (f fakeNodeDiscoverer) FindNodes(_ context.Context) ([]kubernetes.NodeInfo, error) {
+	return f, nil
+}
+
+func createNodeInfo() kubernetes.NodeInfo {
+	return kubernetes.NodeInfo{
+		Name:                    nodeName,
+		InternalIP:              "10.0.0.1",
+		ExternalIP:              "203.0.113.1",
+		Hostname:                "test-node.local",
+		KubeletPort:             10250,
+		OperatingSystem:         "linux",
+		Architecture:            "amd64",
+		KernelVersion:           "6.1.0",
+		OSImage:                 "Ubuntu 22.04",
+		ContainerRuntimeVersion: "containerd://1.7.0",
+		KubeletVersion:          "v1.31.1",
+		Conditions:              kubernetes.ConditionsMap{"Ready": "True"},
+		Labels:                  kubernetes.LabelsMap{"topology.kubernetes.io/zone": "a"},
+		Annotations:             kubernetes.AnnotationsMap{"volumes.kubernetes.io/controller-managed-attach-detach": "true"},
+		Cluster:                 testServiceClusterName,
+	}
+}
+
+func TestProcessNodes(t *testing.T) {
+	output := processNodes([]kubernetes.NodeInfo{createNodeInfo()})
+
+	require.Len(t, output, 1)
+	item := output[0]
+
+	assert.Equal(t, VariablesMap{
+		cluster:                   testServiceClusterName,
+		node:                      nodeName,
+		ip:                        "10.0.0.1",
+		nodeIP:                    "10.0.0.1",
+		externalIP:                "203.0.113.1",
+		hostname:                  "test-node.local",
+		kubeletPort:               int32(10250),
+		operatingSystem:           "linux",
+		architecture:              "amd64",
+		kernelVersion:             "6.1.0",
+		osImage:                   "Ubuntu 22.04",
+		containerRuntime:          "containerd://1.7.0",
+		kubeletVersion:            "v1.31.1",
+		conditionPrefix + "Ready": "True",
+		labelPrefix + "topology.kubernetes.io/zone":                                 "a",
+		annotationPrefix + "volumes.kubernetes.io/controller-managed-attach-detach": "true",
+	}, item.Variables)
+
+	for _, excluded := range []string{ip, nodeIP, externalIP, kubeletPort} {
+		assert.NotContains(t, item.MetricAnnotations, excluded)
+	}
+	assert.Equal(t, nodeName, item.MetricAnnotations[node])
+
+	require.Len(t, item.EntityRewrites, 1)
+	assert.Equal(t, nodeEntityReplaceField, item.EntityRewrites[0].ReplaceField)
+	assert.Equal(t, entityRewriteMatch, item.EntityRewrites[0].Match)
+}
+
+func TestDiscoverer_RunNodes(t *testing.T) {
+	d := &Discoverer{
+		kubelet: fakeKubeletClient(t),
+	}
+	d.SetNodeDiscoverer(fakeNodeDiscoverer{createNodeInfo()})
+
+	output, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	assert.Equal(t, nodeName, output[0].Variables[node])
+	assert.NotContains(t, output[0].Variables, podName, "Only nodes are discovered")
+}
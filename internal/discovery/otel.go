@@ -0,0 +1,180 @@
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	otelObserverName = "k8s_observer"
+
+	otelEndpointTypePod  = "pod"
+	otelEndpointTypePort = "port"
+
+	otelDefaultTransport = "TCP"
+)
+
+// otelPod holds the pod details of the k8s_observer endpoints, as exposed to the receiver_creator rules.
+type otelPod struct {
+	Name        string            `json:"name"`
+	UID         string            `json:"uid"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// otelEndpoint is an endpoint of the OpenTelemetry Collector k8s_observer, either a pod or one of its ports.
+// Pod endpoints carry the pod details at the top level, while port endpoints nest them under pod.
+type otelEndpoint struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Endpoint string `json:"endpoint"`
+
+	// pod endpoints
+	UID         string            `json:"uid,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// pod and port endpoints
+	Name string `json:"name"`
+
+	// port endpoints
+	Port          int      `json:"port,omitempty"`
+	Transport     string   `json:"transport,omitempty"`
+	ContainerName string   `json:"container_name,omitempty"`
+	Pod           *otelPod `json:"pod,omitempty"`
+}
+
+// otelEndpoints returns a pod endpoint for each pod discovered, followed by an endpoint for each of its container ports,
+// as the k8s_observer reports them. Items other than containers, or without an address, are skipped.
+func otelEndpoints(output Output) []otelEndpoint {
+	// default empty, instead of nil.
+	endpoints := []otelEndpoint{}
+	seen := map[string]bool{}
+
+	for _, item := range output {
+		v := item.Variables
+		podIP, _ := v[ip].(string)
+		if item.kind != kindPod || podIP == "" {
+			continue
+		}
+
+		pod := otelPodDetails(item)
+		podID := otelObserverName + "/" + pod.UID
+		if pod.UID == "" {
+			podID = otelObserverName + "/" + pod.Namespace + "/" + pod.Name
+		}
+
+		if !seen[podID] {
+			seen[podID] = true
+			endpoints = append(endpoints, otelEndpoint{
+				ID:          podID,
+				Type:        otelEndpointTypePod,
+				Endpoint:    podIP,
+				Name:        pod.Name,
+				UID:         pod.UID,
+				Namespace:   pod.Namespace,
+				Labels:      pod.Labels,
+				Annotations: pod.Annotations,
+			})
+		}
+
+		containerName := fmt.Sprint(v[name])
+		for _, port := range containerPorts(itemPorts(item)) {
+			// IDs follow the k8s_observer ones, e.g. k8s_observer/<pod uid>/http(8080).
+			portID := fmt.Sprintf("%s/%s(%d)", podID, port.name, port.number)
+			if seen[portID] {
+				continue
+			}
+			seen[portID] = true
+
+			transport := item.portProtocols[port.name]
+			if transport == "" {
+				transport = otelDefaultTransport
+			}
+
+			endpoints = append(endpoints, otelEndpoint{
+				ID:            portID,
+				Type:          otelEndpointTypePort,
+				Endpoint:      net.JoinHostPort(podIP, strconv.Itoa(port.number)),
+				Name:          port.name,
+				Port:          port.number,
+				Transport:     transport,
+				ContainerName: containerName,
+				Pod:           &pod,
+			})
+		}
+	}
+
+	return endpoints
+}
+
+func otelPodDetails(item DiscoveredItem) otelPod {
+	pod := otelPod{
+		Name:        fmt.Sprint(item.Variables[podName]),
+		UID:         item.podUID,
+		Namespace:   fmt.Sprint(item.Variables[namespace]),
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+
+	for k, value := range item.Variables {
+		switch {
+		case strings.HasPrefix(k, labelPrefix):
+			pod.Labels[strings.TrimPrefix(k, labelPrefix)] = fmt.Sprint(value)
+		case strings.HasPrefix(k, annotationPrefix):
+			pod.Annotations[strings.TrimPrefix(k, annotationPrefix)] = fmt.Sprint(value)
+		}
+	}
+
+	return pod
+}
+
+// containerPort is a port of a container, named after the port name if set, or its index otherwise as in the
+// kubernetes.PortsMap. The name is the key the port and its protocol are indexed by in the item.
+type containerPort struct {
+	name   string
+	number int
+}
+
+// named tells whether the port is named after the port name rather than its index.
+func (p containerPort) named() bool {
+	return !isPortIndex(p.name)
+}
+
+func isPortIndex(name string) bool {
+	_, err := strconv.Atoi(name)
+	return err == nil
+}
+
+// containerPorts returns each port once, preferring its name over the positional index.
+func containerPorts(byName map[string]int) []containerPort {
+	byNumber := map[int]containerPort{}
+	for key, number := range byName {
+		port, found := byNumber[number]
+		if !found || portNamePrecedes(key, port.name) {
+			port = containerPort{name: key, number: number}
+		}
+		byNumber[number] = port
+	}
+
+	result := make([]containerPort, 0, len(byNumber))
+	for _, port := range byNumber {
+		result = append(result, port)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].number < result[j].number })
+	return result
+}
+
+// portNamePrecedes tells whether the name is preferred over the current one of the same port: names over indexes,
+// and then the lowest one, so the same port is always named the same.
+func portNamePrecedes(name, current string) bool {
+	if isIndex, currentIsIndex := isPortIndex(name), isPortIndex(current); isIndex != currentIsIndex {
+		return currentIsIndex
+	}
+	return name < current
+}
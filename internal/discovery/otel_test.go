@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func otelContainerItem(container string, byName kubernetes.PortsMap, protocols kubernetes.ProtocolsMap) DiscoveredItem {
+	item := containerItem("nginx", container, "nginx:1.25")
+	item.Variables[ip] = "10.1.0.1"
+	item.Variables[ports] = byName
+	item.podUID = "1234"
+	item.portProtocols = protocols
+	item.Variables[labelPrefix+"app"] = "nginx"
+	item.Variables[annotationPrefix+"team"] = "caos"
+	return item
+}
+
+func TestOTelEndpoints(t *testing.T) {
+	nginx := otelContainerItem("nginx",
+		kubernetes.PortsMap{"0": 8080, "http": 8080, "1": 8125},
+		kubernetes.ProtocolsMap{"0": "TCP", "http": "TCP", "1": "UDP"},
+	)
+	exporter := otelContainerItem("exporter", kubernetes.PortsMap{"0": 9113, "metrics": 9113}, nil)
+
+	pod := &otelPod{
+		Name:        "nginx",
+		UID:         "1234",
+		Namespace:   "default",
+		Labels:      map[string]string{"app": "nginx"},
+		Annotations: map[string]string{"team": "caos"},
+	}
+	expected := []otelEndpoint{
+		{
+			ID:          "k8s_observer/1234",
+			Type:        "pod",
+			Endpoint:    "10.1.0.1",
+			Name:        "nginx",
+			UID:         "1234",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "nginx"},
+			Annotations: map[string]string{"team": "caos"},
+		},
+		{
+			ID:            "k8s_observer/1234/http(8080)",
+			Type:          "port",
+			Endpoint:      "10.1.0.1:8080",
+			Name:          "http",
+			Port:          8080,
+			Transport:     "TCP",
+			ContainerName: "nginx",
+			Pod:           pod,
+		},
+		{
+			ID:            "k8s_observer/1234/1(8125)",
+			Type:          "port",
+			Endpoint:      "10.1.0.1:8125",
+			Name:          "1",
+			Port:          8125,
+			Transport:     "UDP",
+			ContainerName: "nginx",
+			Pod:           pod,
+		},
+		{
+			ID:            "k8s_observer/1234/metrics(9113)",
+			Type:          "port",
+			Endpoint:      "10.1.0.1:9113",
+			Name:          "metrics",
+			Port:          9113,
+			Transport:     "TCP",
+			ContainerName: "exporter",
+			Pod:           pod,
+		},
+	}
+
+	assert.Equal(t, expected, otelEndpoints(Output{nginx, exporter}))
+}
+
+func TestOTelEndpoints_Skipped(t *testing.T) {
+	service := DiscoveredItem{Variables: VariablesMap{serviceName: "redis", clusterIP: "10.96.0.1", ports: []kubernetes.ServicePortInfo{{Port: 6379}}}}
+	node := DiscoveredItem{Variables: VariablesMap{node: "node", ip: "10.0.0.1", kubeletPort: int32(10250)}}
+	pending := containerItem("pending", "pending", "app:1")
+
+	assert.Empty(t, otelEndpoints(Output{service, node, pending}))
+}
+
+func TestRenderer_OTelK8sObserver(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatOTelK8sObserver})
+
+	item := otelContainerItem("nginx", kubernetes.PortsMap{"0": 8080, "http": 8080}, kubernetes.ProtocolsMap{"0": "TCP", "http": "TCP"})
+	data, err := r.Render(Output{item})
+	require.NoError(t, err)
+
+	// The output served from the output cache renders the same endpoints.
+	oc := NewOutputCache(testOutputCacheConfig(t))
+	require.NoError(t, oc.Store(Output{item}))
+	cached, _, err := oc.Load()
+	require.NoError(t, err)
+
+	fromCache, err := r.Render(cached)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(fromCache))
+
+	var endpoints []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &endpoints))
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "10.1.0.1:8080", endpoints[1]["endpoint"])
+	assert.Equal(t, map[string]interface{}{
+		"name":        "nginx",
+		"uid":         "1234",
+		"namespace":   "default",
+		"labels":      map[string]interface{}{"app": "nginx"},
+		"annotations": map[string]interface{}{"team": "caos"},
+	}, endpoints[1]["pod"])
+}
@@ -0,0 +1,130 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
+)
+
+var (
+	ErrCachedOutputExpired  = errors.New("cached output is older than the maximum age")
+	ErrCachedOutputMismatch = errors.New("cached output was stored with a different configuration")
+)
+
+// cachedOutput is the output stored in the output cache file.
+type cachedOutput struct {
+	// Key identifies the configuration used to discover the output, so changes invalidate it.
+	Key      string       `json:"key"`
+	StoredAt time.Time    `json:"storedAt"`
+	Output   []cachedItem `json:"output"`
+}
+
+// cachedItem is an item stored in the output cache file, along with its kind and the pod details kept out of its
+// variables, so cached items are rendered as the ones just discovered.
+type cachedItem struct {
+	DiscoveredItem
+	Kind          string                  `json:"kind"`
+	PodUID        string                  `json:"podUID,omitempty"`
+	PortProtocols kubernetes.ProtocolsMap `json:"portProtocols,omitempty"`
+}
+
+// OutputCache keeps the last successful output on disk, so it can be served while discovery fails,
+// e.g. during a kubelet restart.
+type OutputCache struct {
+	file   string
+	maxAge time.Duration
+	key    string
+}
+
+// NewOutputCache creates the output cache configured, which is nil if no output cache file is set.
+func NewOutputCache(c *config.Config) *OutputCache {
+	if c.OutputCacheFile == "" {
+		return nil
+	}
+
+	return &OutputCache{
+		file:   c.OutputCacheFile,
+		maxAge: c.OutputCacheMaxAge,
+		key: fmt.Sprintf("%s|%s|%s|%t|%t|%t|%s|%s",
+			c.ClusterName, c.NodeName, strings.Join(c.Namespaces, ","),
+			c.DiscoverServices, c.DiscoverCustomResources, c.DiscoverNodes, c.PodsSource,
+			declarationsHash(c),
+		),
+	}
+}
+
+// declarationsHash hashes the settings declared in the configuration, rules and custom resources files which shape
+// the items discovered, e.g. the selectors and rules, so changing any of them also invalidates the cached output.
+func declarationsHash(c *config.Config) string {
+	data, _ := json.Marshal(struct { // nolint: errcheck // settings were read from YAML
+		Contexts         []config.ClusterContext
+		Selectors        config.Selectors
+		AnnotationFilter config.AnnotationFilter
+		EntityRewrites   config.EntityRewriteTemplates
+		CustomResources  []config.CustomResource
+		Rules            []config.Rule
+		RulesOnly        bool
+	}{c.Contexts, c.Selectors, c.AnnotationFilter, c.EntityRewrites, c.CustomResources, c.Rules, c.RulesOnly})
+	return shortHash(data)
+}
+
+// Store replaces the cached output.
+func (oc *OutputCache) Store(output Output) error {
+	cached := cachedOutput{
+		Key:      oc.key,
+		StoredAt: time.Now(),
+		Output:   make([]cachedItem, 0, len(output)),
+	}
+	for _, item := range output {
+		cached.Output = append(cached.Output, cachedItem{DiscoveredItem: item, Kind: item.kind, PodUID: item.podUID, PortProtocols: item.portProtocols})
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("marshaling output to cache: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(oc.file, data, 0o600); err != nil {
+		return fmt.Errorf("storing output in %q: %w", oc.file, err)
+	}
+
+	return nil
+}
+
+// Load returns the cached output with every item flagged as stale, along with the time it was stored.
+func (oc *OutputCache) Load() (Output, time.Time, error) {
+	data, err := os.ReadFile(oc.file)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("reading output cache: %w", err)
+	}
+
+	cached := cachedOutput{}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing output cache: %w", err)
+	}
+
+	if cached.Key != oc.key {
+		return nil, time.Time{}, ErrCachedOutputMismatch
+	}
+
+	if time.Since(cached.StoredAt) > oc.maxAge {
+		return nil, cached.StoredAt, ErrCachedOutputExpired
+	}
+
+	output := Output{}
+	for _, c := range cached.Output {
+		item := c.DiscoveredItem
+		item.Stale = true
+		item.kind, item.podUID, item.portProtocols = c.Kind, c.PodUID, c.PortProtocols
+		output = append(output, item)
+	}
+
+	return output, cached.StoredAt, nil
+}
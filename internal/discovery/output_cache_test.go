@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOutputCacheConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	return &config.Config{
+		ClusterName:       testServiceClusterName,
+		NodeName:          nodeName,
+		Namespaces:        []string{"default"},
+		PodsSource:        config.PodsSourceKubelet,
+		OutputCacheFile:   filepath.Join(t.TempDir(), "output.json"),
+		OutputCacheMaxAge: time.Minute,
+	}
+}
+
+func TestNewOutputCache_NotConfigured(t *testing.T) {
+	assert.Nil(t, NewOutputCache(&config.Config{}))
+}
+
+func TestOutputCache(t *testing.T) {
+	output := Output{
+		{
+			Variables:         VariablesMap{podName: "nginx", ports: map[string]interface{}{"http": 80}},
+			MetricAnnotations: AnnotationsMap{namespace: "default"},
+			EntityRewrites:    []Replacement{{Action: "replace", Match: "${ip}", ReplaceField: "k8s:${clusterName}"}},
+		},
+	}
+
+	t.Run("served_as_stale", func(t *testing.T) {
+		oc := NewOutputCache(testOutputCacheConfig(t))
+		require.NoError(t, oc.Store(output))
+
+		cached, storedAt, err := oc.Load()
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), storedAt, time.Minute)
+		require.Len(t, cached, 1)
+		assert.True(t, cached[0].Stale)
+
+		// Apart from the stale flag, the output printed is the same.
+		cached[0].Stale = false
+		expected, err := json.Marshal(output)
+		require.NoError(t, err)
+		actual, err := json.Marshal(cached)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := testOutputCacheConfig(t)
+		c.OutputCacheMaxAge = time.Nanosecond
+		oc := NewOutputCache(c)
+		require.NoError(t, oc.Store(output))
+
+		time.Sleep(time.Millisecond)
+		_, _, err := oc.Load()
+		assert.ErrorIs(t, err, ErrCachedOutputExpired)
+	})
+
+	for name, change := range map[string]func(c *config.Config){
+		"namespaces": func(c *config.Config) { c.Namespaces = []string{"other"} },
+		"contexts":   func(c *config.Config) { c.Contexts = []config.ClusterContext{{Context: "prod", ClusterName: "prod"}} },
+		"selectors":  func(c *config.Config) { c.Selectors.Labels = "app=nginx" },
+		"rules":      func(c *config.Config) { c.Rules = []config.Rule{{Name: "nginx"}} },
+		"rules_only": func(c *config.Config) { c.RulesOnly = true },
+		"custom_resources": func(c *config.Config) {
+			c.CustomResources = []config.CustomResource{{APIVersion: "example.com/v1", Resource: "widgets"}}
+		},
+	} {
+		t.Run("different_"+name, func(t *testing.T) {
+			c := testOutputCacheConfig(t)
+			require.NoError(t, NewOutputCache(c).Store(output))
+
+			change(c)
+			_, _, err := NewOutputCache(c).Load()
+			assert.ErrorIs(t, err, ErrCachedOutputMismatch)
+		})
+	}
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, _, err := NewOutputCache(testOutputCacheConfig(t)).Load()
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
+)
+
+const (
+	outputDirPerm  = 0o755
+	outputFilePerm = 0o644
+)
+
+// itemFileName matches the files written for each item, named by their discoveryID, so other files in the
+// directory are never removed.
+var itemFileName = regexp.MustCompile(fmt.Sprintf(`^[0-9a-f]{%d}\.json$`, hashLength))
+
+// WriteOutputFile replaces the file with the data atomically.
+func WriteOutputFile(file string, data []byte) error {
+	if err := utils.WriteFileAtomic(file, data, outputFilePerm); err != nil {
+		return fmt.Errorf("writing output file %q: %w", file, err)
+	}
+	return nil
+}
+
+// WriteOutputDir writes each item as JSON to its own file in the directory, named by its discoveryID, and removes
+// the files of items no longer discovered. Files are replaced atomically, and only when their content changes.
+func WriteOutputDir(dir string, output Output) error {
+	if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+		return fmt.Errorf("creating output directory %q: %w", dir, err)
+	}
+
+	var errs []error
+	written := map[string]bool{}
+	for _, item := range output {
+		id, ok := item.Variables[discoveryID].(string)
+		if !ok || id == "" {
+			continue
+		}
+
+		fileName := id + ".json"
+		written[fileName] = true
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("marshaling item %s: %w", id, err))
+			continue
+		}
+
+		file := filepath.Join(dir, fileName)
+		if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+
+		if err := utils.WriteFileAtomic(file, data, outputFilePerm); err != nil {
+			errs = append(errs, fmt.Errorf("writing item %s: %w", id, err))
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("reading output directory %q: %w", dir, err))...)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !itemFileName.MatchString(entry.Name()) || written[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("removing stale item file: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
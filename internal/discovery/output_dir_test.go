@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutputFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "output.json")
+
+	require.NoError(t, WriteOutputFile(file, []byte("[]\n")))
+	require.NoError(t, WriteOutputFile(file, []byte("[{}]\n")))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "[{}]\n", string(data))
+
+	entries, err := os.ReadDir(filepath.Dir(file))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "No temporary files are left behind")
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "items")
+
+	nginx := containerItem("nginx", "nginx", "nginx:1.25")
+	redis := containerItem("redis", "redis", "redis:7")
+	output := Output{nginx, redis}
+	identify(output)
+
+	require.NoError(t, WriteOutputDir(dir, output))
+
+	nginxFile := filepath.Join(dir, nginx.Variables[discoveryID].(string)+".json")
+	redisFile := filepath.Join(dir, redis.Variables[discoveryID].(string)+".json")
+
+	data, err := os.ReadFile(nginxFile)
+	require.NoError(t, err)
+	var item DiscoveredItem
+	require.NoError(t, json.Unmarshal(data, &item))
+	assert.Equal(t, "nginx", item.Variables[podName])
+
+	t.Run("unchanged_items_are_not_rewritten", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(nginxFile, past, past))
+
+		require.NoError(t, WriteOutputDir(dir, output))
+
+		info, err := os.Stat(nginxFile)
+		require.NoError(t, err)
+		assert.True(t, info.ModTime().Equal(past))
+	})
+
+	t.Run("stale_items_are_removed", func(t *testing.T) {
+		other := filepath.Join(dir, "README.md")
+		require.NoError(t, os.WriteFile(other, []byte("not an item"), 0o600))
+
+		require.NoError(t, WriteOutputDir(dir, Output{nginx}))
+
+		assert.FileExists(t, nginxFile)
+		assert.NoFileExists(t, redisFile)
+		assert.FileExists(t, other, "Files not written for items are kept")
+	})
+}
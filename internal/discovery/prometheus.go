@@ -0,0 +1,189 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	prometheusScrapeAnnotation = annotationPrefix + "prometheus.io/scrape"
+	prometheusPortAnnotation   = annotationPrefix + "prometheus.io/port"
+	prometheusPathAnnotation   = annotationPrefix + "prometheus.io/path"
+	prometheusSchemeAnnotation = annotationPrefix + "prometheus.io/scheme"
+
+	prometheusMetricsPathLabel = "__metrics_path__"
+	prometheusSchemeLabel      = "__scheme__"
+)
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// volatileLabels are the variables not exported as labels.
+var volatileLabels = map[Property]bool{
+	id:          true,
+	discoveryID: true,
+	contentHash: true,
+}
+
+// prometheusTargetGroup is a target group of the Prometheus file based service discovery.
+type prometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// prometheusTargetGroups returns a target group for each item having an address and ports.
+// Targets are the ports set in the prometheus.io/port annotation, the one named portName, or every port otherwise.
+// Items annotated with prometheus.io/scrape=false are skipped. Since every container of a pod shares its annotations,
+// an annotated port is only targeted by the first container of the pod.
+func prometheusTargetGroups(output Output, portName string) []prometheusTargetGroup {
+	// default empty, instead of nil.
+	groups := []prometheusTargetGroup{}
+	annotatedTargets := map[string]bool{}
+
+	for _, item := range output {
+		v := item.Variables
+		if fmt.Sprint(v[prometheusScrapeAnnotation]) == "false" {
+			continue
+		}
+
+		host := itemHost(item)
+		if host == "" {
+			continue
+		}
+
+		var targetPorts []int
+		port, annotated := v[prometheusPortAnnotation]
+		switch {
+		case annotated:
+			if p, err := strconv.Atoi(fmt.Sprint(port)); err == nil {
+				targetPorts = []int{p}
+			}
+		case portName != "":
+			if p, found := itemPorts(item)[portName]; found {
+				targetPorts = []int{p}
+			}
+		default:
+			targetPorts = uniquePorts(itemPorts(item))
+		}
+
+		group := prometheusTargetGroup{Labels: prometheusLabels(v)}
+		for _, p := range targetPorts {
+			target := net.JoinHostPort(host, strconv.Itoa(p))
+			if annotated {
+				if annotatedTargets[target] {
+					continue
+				}
+				annotatedTargets[target] = true
+			}
+			group.Targets = append(group.Targets, target)
+		}
+
+		if len(group.Targets) == 0 {
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// itemHost returns the address of the item, the cluster IP for services.
+func itemHost(item DiscoveredItem) string {
+	for _, property := range []Property{ip, clusterIP} {
+		host, ok := item.Variables[property].(string)
+		if ok && host != "" && host != "None" {
+			return host
+		}
+	}
+	return ""
+}
+
+// itemPorts returns the ports of the item by name. Container ports are also indexed by position,
+// while nodes expose the kubelet one.
+func itemPorts(item DiscoveredItem) map[string]int {
+	result := map[string]int{}
+
+	if port, ok := item.Variables[kubeletPort]; ok {
+		if p, err := strconv.Atoi(fmt.Sprint(port)); err == nil {
+			result["kubelet"] = p
+		}
+	}
+
+	value, ok := item.Variables[ports]
+	if !ok {
+		return result
+	}
+
+	// Ports are marshaled to read them the same way whether they were just discovered or read from a file.
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+
+	byName := map[string]int{}
+	if json.Unmarshal(data, &byName) == nil {
+		for name, p := range byName {
+			result[name] = p
+		}
+		return result
+	}
+
+	var servicePorts []struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	if json.Unmarshal(data, &servicePorts) == nil {
+		for i, p := range servicePorts {
+			result[strconv.Itoa(i)] = p.Port
+			if p.Name != "" {
+				result[p.Name] = p.Port
+			}
+		}
+	}
+
+	return result
+}
+
+func uniquePorts(byName map[string]int) []int {
+	seen := map[int]bool{}
+	var result []int
+	for _, p := range byName {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// prometheusLabels returns the scalar variables as labels, with their names sanitized. Annotations are left out,
+// since they usually hold large values, except the ones setting the metrics path and scheme. Variables changing
+// whenever a container restarts are left out too, so the series of the targets are not churned.
+func prometheusLabels(v VariablesMap) map[string]string {
+	labels := map[string]string{}
+
+	for name, value := range v {
+		if strings.HasPrefix(name, annotationPrefix) || volatileLabels[name] {
+			continue
+		}
+
+		switch value.(type) {
+		case string, bool, int, int32, int64, float64:
+			labels[invalidLabelChars.ReplaceAllString(name, "_")] = fmt.Sprint(value)
+		}
+	}
+
+	if path, ok := v[prometheusPathAnnotation]; ok {
+		labels[prometheusMetricsPathLabel] = fmt.Sprint(path)
+	}
+	if scheme, ok := v[prometheusSchemeAnnotation]; ok {
+		labels[prometheusSchemeLabel] = fmt.Sprint(scheme)
+	}
+
+	return labels
+}
@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prometheusContainerItem(annotations map[string]string) DiscoveredItem {
+	item := containerItem("nginx", "nginx", "nginx:1.25")
+	item.Variables[ip] = "10.1.0.1"
+	item.Variables[ports] = kubernetes.PortsMap{"0": 8080, "http": 8080, "1": 9113, "metrics": 9113}
+	item.Variables[labelPrefix+"app.kubernetes.io/name"] = "nginx"
+	for k, v := range annotations {
+		item.Variables[annotationPrefix+k] = v
+	}
+	return item
+}
+
+func TestPrometheusTargetGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		item        DiscoveredItem
+		portName    string
+		wantTargets []string
+	}{
+		{
+			name:        "every_port",
+			item:        prometheusContainerItem(nil),
+			wantTargets: []string{"10.1.0.1:8080", "10.1.0.1:9113"},
+		},
+		{
+			name:        "port_by_name",
+			item:        prometheusContainerItem(nil),
+			portName:    "metrics",
+			wantTargets: []string{"10.1.0.1:9113"},
+		},
+		{
+			name:        "port_annotation_takes_precedence",
+			item:        prometheusContainerItem(map[string]string{"prometheus.io/port": "9100"}),
+			portName:    "metrics",
+			wantTargets: []string{"10.1.0.1:9100"},
+		},
+		{
+			name: "service_ports",
+			item: DiscoveredItem{Variables: VariablesMap{
+				serviceName: "redis",
+				clusterIP:   "10.96.0.1",
+				ports:       []kubernetes.ServicePortInfo{{Name: "redis", Port: 6379}, {Name: "metrics", Port: 9121}},
+			}},
+			portName:    "metrics",
+			wantTargets: []string{"10.96.0.1:9121"},
+		},
+		{
+			name:        "node_kubelet_port",
+			item:        DiscoveredItem{Variables: VariablesMap{node: "node", ip: "10.0.0.1", kubeletPort: int32(10250)}},
+			wantTargets: []string{"10.0.0.1:10250"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := prometheusTargetGroups(Output{tt.item}, tt.portName)
+			require.Len(t, groups, 1)
+			assert.Equal(t, tt.wantTargets, groups[0].Targets)
+		})
+	}
+}
+
+func TestPrometheusTargetGroups_Skipped(t *testing.T) {
+	headless := DiscoveredItem{Variables: VariablesMap{serviceName: "headless", clusterIP: "None", ports: []kubernetes.ServicePortInfo{{Port: 80}}}}
+	notScraped := prometheusContainerItem(map[string]string{"prometheus.io/scrape": "false"})
+	missingPort := prometheusContainerItem(nil)
+
+	assert.Empty(t, prometheusTargetGroups(Output{headless, notScraped}, ""))
+	assert.Empty(t, prometheusTargetGroups(Output{missingPort}, "missing"))
+}
+
+func TestPrometheusTargetGroups_TwoContainerPod(t *testing.T) {
+	annotations := map[string]string{"prometheus.io/port": "9113"}
+	nginx := prometheusContainerItem(annotations)
+	exporter := prometheusContainerItem(annotations)
+	exporter.Variables[name] = "exporter"
+	exporter.Variables[ports] = kubernetes.PortsMap{"0": 9113, "metrics": 9113}
+
+	groups := prometheusTargetGroups(Output{nginx, exporter}, "")
+	require.Len(t, groups, 1, "Annotated port is targeted once per pod")
+	assert.Equal(t, []string{"10.1.0.1:9113"}, groups[0].Targets)
+	assert.Equal(t, "nginx", groups[0].Labels["name"])
+
+	groups = prometheusTargetGroups(Output{prometheusContainerItem(nil), exporter}, "")
+	require.Len(t, groups, 2, "Ports of each container are targeted without the annotation")
+	assert.Equal(t, []string{"10.1.0.1:8080", "10.1.0.1:9113"}, groups[0].Targets)
+	assert.Equal(t, []string{"10.1.0.1:9113"}, groups[1].Targets)
+}
+
+func TestPrometheusLabels(t *testing.T) {
+	item := prometheusContainerItem(map[string]string{"prometheus.io/path": "/stats", "prometheus.io/scheme": "https", "big": "annotation"})
+	item.Variables[id] = "containerd://1a2b3c"
+	item.Variables[discoveryID] = "4d5e6f"
+	item.Variables[contentHash] = "7a8b9c"
+
+	groups := prometheusTargetGroups(Output{item}, "")
+	require.Len(t, groups, 1)
+
+	labels := groups[0].Labels
+	assert.Equal(t, "nginx", labels["podName"])
+	assert.Equal(t, "default", labels["namespace"])
+	assert.Equal(t, "nginx", labels["label_app_kubernetes_io_name"], "Label names are sanitized")
+	assert.Equal(t, "/stats", labels["__metrics_path__"])
+	assert.Equal(t, "https", labels["__scheme__"])
+	assert.NotContains(t, labels, "ports", "Only scalar variables become labels")
+	assert.NotContains(t, labels, "annotation_big", "Annotations are left out")
+	assert.NotContains(t, labels, "id", "Container id changes on restarts")
+	assert.NotContains(t, labels, "discoveryID")
+	assert.NotContains(t, labels, "contentHash")
+}
+
+func TestRenderer_PrometheusFileSD(t *testing.T) {
+	r := NewRenderer(&config.Config{OutputFormat: config.OutputFormatPrometheusFileSD, PrometheusPortName: "http"})
+
+	data, err := r.Render(Output{prometheusContainerItem(nil)})
+	require.NoError(t, err)
+
+	var groups []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &groups))
+	require.Len(t, groups, 1)
+	assert.Equal(t, []interface{}{"10.1.0.1:8080"}, groups[0]["targets"])
+
+	data, err = r.Render(Output{})
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(data), "No targets are printed as an empty list")
+}
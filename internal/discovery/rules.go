@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const ruleIntegration = "integration"
+
+// rule is a config.Rule with its matchers parsed.
+type rule struct {
+	name        string
+	variables   map[string]string
+	image       *regexp.Regexp
+	selector    labels.Selector
+	portName    string
+	annotations map[string]*regexp.Regexp
+}
+
+// matches returns whether every matcher of the rule matches the item.
+func (r rule) matches(item DiscoveredItem) bool {
+	v := item.Variables
+
+	if r.image != nil {
+		img, ok := v[image].(string)
+		if !ok || !r.image.MatchString(img) {
+			return false
+		}
+	}
+
+	if r.selector != nil && !r.selector.Matches(itemLabels(v)) {
+		return false
+	}
+
+	if r.portName != "" {
+		if _, found := itemPorts(item)[r.portName]; !found {
+			return false
+		}
+	}
+
+	for annotation, value := range r.annotations {
+		actual, found := v[annotationPrefix+annotation]
+		if !found || !value.MatchString(fmt.Sprint(actual)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Rules match discovered items to the integrations run for them.
+type Rules struct {
+	rules []rule
+	only  bool
+}
+
+// NewRules parses the rules declared in the config, which are nil if there are none.
+func NewRules(c *config.Config) (*Rules, error) {
+	if len(c.Rules) == 0 {
+		return nil, nil
+	}
+
+	parsed := &Rules{only: c.RulesOnly}
+	for _, cr := range c.Rules {
+		r := rule{
+			name:        cr.Name,
+			variables:   map[string]string{ruleIntegration: cr.Integration},
+			portName:    cr.Match.PortName,
+			annotations: make(map[string]*regexp.Regexp, len(cr.Match.Annotations)),
+		}
+		for k, value := range cr.Variables {
+			r.variables[k] = value
+		}
+
+		var err error
+		if cr.Match.Image != "" {
+			if r.image, err = regexp.Compile(cr.Match.Image); err != nil {
+				return nil, fmt.Errorf("parsing image of rule %q: %w", cr.Name, err)
+			}
+		}
+		if cr.Match.LabelSelector != "" {
+			if r.selector, err = labels.Parse(cr.Match.LabelSelector); err != nil {
+				return nil, fmt.Errorf("parsing label selector of rule %q: %w", cr.Name, err)
+			}
+		}
+		for annotation, value := range cr.Match.Annotations {
+			if r.annotations[annotation], err = regexp.Compile(value); err != nil {
+				return nil, fmt.Errorf("parsing annotation %q of rule %q: %w", annotation, cr.Name, err)
+			}
+		}
+
+		parsed.rules = append(parsed.rules, r)
+	}
+
+	return parsed, nil
+}
+
+// apply sets the matchedRules variable of each item to the comma separated names of the rules it matches, in the
+// order they are declared, and the rules variable to the integration and variables of each of them by rule name.
+// Items not matching any rule are dropped when only matched items are printed.
+func (rs *Rules) apply(output Output) Output {
+	result := make(Output, 0, len(output))
+
+	for _, item := range output {
+		var matched []string
+		byRule := map[string]map[string]string{}
+		for _, r := range rs.rules {
+			if r.matches(item) {
+				matched = append(matched, r.name)
+				byRule[r.name] = r.variables
+			}
+		}
+
+		if len(matched) == 0 && rs.only {
+			continue
+		}
+
+		item.Variables[matchedRules] = strings.Join(matched, ",")
+		if len(byRule) > 0 {
+			item.Variables[ruleVariables] = byRule
+		}
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// itemLabels returns the labels of the item, without their variable prefix.
+func itemLabels(v VariablesMap) labels.Set {
+	set := labels.Set{}
+	for k, value := range v {
+		if name, isLabel := strings.CutPrefix(k, labelPrefix); isLabel {
+			set[name] = fmt.Sprint(value)
+		}
+	}
+	return set
+}
@@ -0,0 +1,118 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRules(only bool) *config.Config {
+	return &config.Config{
+		RulesOnly: only,
+		Rules: []config.Rule{
+			{
+				Name:        "redis",
+				Integration: "nri-redis",
+				Variables:   map[string]string{"keyspaces": "db0"},
+				Match:       config.RuleMatch{Image: "^redis:"},
+			},
+			{
+				Name:        "exporter",
+				Integration: "nri-prometheus",
+				Match: config.RuleMatch{
+					LabelSelector: "team in (caos,platform)",
+					PortName:      "metrics",
+					Annotations:   map[string]string{"prometheus.io/scrape": "^true$"},
+				},
+			},
+		},
+	}
+}
+
+func TestRules_Apply(t *testing.T) {
+	redis := containerItem("redis", "redis", "redis:7")
+
+	exporter := containerItem("exporter", "exporter", "redis-exporter:1")
+	exporter.Variables[labelPrefix+"team"] = "caos"
+	exporter.Variables[annotationPrefix+"prometheus.io/scrape"] = "true"
+	exporter.Variables[ports] = kubernetes.PortsMap{"0": 9121, "metrics": 9121}
+
+	both := containerItem("both", "redis", "redis:7")
+	for k, v := range exporter.Variables {
+		if k != image {
+			both.Variables[k] = v
+		}
+	}
+
+	notScraped := containerItem("not-scraped", "exporter", "redis-exporter:1")
+	notScraped.Variables[labelPrefix+"team"] = "caos"
+	notScraped.Variables[ports] = kubernetes.PortsMap{"metrics": 9121}
+
+	rules, err := NewRules(testRules(false))
+	require.NoError(t, err)
+
+	output := rules.apply(Output{redis, exporter, both, notScraped})
+	require.Len(t, output, 4)
+
+	assert.Equal(t, "redis", output[0].Variables[matchedRules])
+	assert.Equal(t, map[string]map[string]string{
+		"redis": {"integration": "nri-redis", "keyspaces": "db0"},
+	}, output[0].Variables[ruleVariables])
+
+	assert.Equal(t, "exporter", output[1].Variables[matchedRules])
+	assert.Equal(t, "redis,exporter", output[2].Variables[matchedRules], "Rules are listed in the order they are declared")
+
+	assert.Equal(t, "", output[3].Variables[matchedRules], "Every matcher of a rule must match")
+	assert.NotContains(t, output[3].Variables, ruleVariables)
+}
+
+func TestRules_ApplyOnlyMatched(t *testing.T) {
+	rules, err := NewRules(testRules(true))
+	require.NoError(t, err)
+
+	output := rules.apply(Output{containerItem("nginx", "nginx", "nginx:1.25"), containerItem("redis", "redis", "redis:7")})
+	require.Len(t, output, 1)
+	assert.Equal(t, "redis", output[0].Variables[podName])
+}
+
+func TestNewRules(t *testing.T) {
+	rules, err := NewRules(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, rules, "No rules are applied unless declared")
+
+	tests := map[string]config.RuleMatch{
+		"invalid_image":          {Image: "redis("},
+		"invalid_label_selector": {LabelSelector: "app in (redis"},
+		"invalid_annotation":     {Annotations: map[string]string{"scrape": "["}},
+	}
+	for name, match := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRules(&config.Config{Rules: []config.Rule{{Name: name, Integration: "nri-redis", Match: match}}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDiscoverer_RunWithRules(t *testing.T) {
+	kubelet := fakePartialKubelet{containers: []kubernetes.ContainerInfo{
+		{Name: "redis", PodName: "redis", Namespace: "default", Image: "redis:7"},
+		{Name: "nginx", PodName: "nginx", Namespace: "default", Image: "nginx:1.25"},
+	}}
+
+	rules, err := NewRules(testRules(true))
+	require.NoError(t, err)
+
+	d := NewDiscoverer(nil, kubelet, false)
+	d.SetRules(rules)
+
+	output, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	assert.Equal(t, "redis", output[0].Variables[matchedRules])
+	assert.NotContains(t, output[0].MetricAnnotations, matchedRules)
+	assert.NotEmpty(t, output[0].Variables[contentHash], "Rules are applied before items are identified")
+}
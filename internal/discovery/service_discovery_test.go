@@ -12,8 +12,6 @@ const (
 	testServiceClusterName = "test-service-cluster"
 )
 
-// Note: Testing with fake Kubernetes clientset is challenging due to type incompatibility
-// between *fake.Clientset and *kubernetes.Clientset in the production code.
 // These tests focus on the processServices function which contains the core discovery logic.
 
 func TestProcessServices(t *testing.T) {
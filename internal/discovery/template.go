@@ -0,0 +1,173 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+var ErrTemplateWithoutIntegrations = errors.New("template does not declare any integration")
+
+// discoveryPlaceholder matches the ${discovery.<variable>} placeholders replaced by the infra agent.
+var discoveryPlaceholder = regexp.MustCompile(`\$\{discovery\.([^}]+)\}`)
+
+// templateFile is the part of an infra agent integrations config used to render it.
+type templateFile struct {
+	Discovery struct {
+		Command struct {
+			Match map[string]interface{} `json:"match"`
+		} `json:"command"`
+	} `json:"discovery"`
+	Integrations []interface{} `json:"integrations"`
+}
+
+// matcher matches a variable either by value, or by a regular expression when written between slashes, e.g. /^nginx/.
+type matcher struct {
+	variable string
+	value    string
+	regexp   *regexp.Regexp
+}
+
+func (m matcher) matches(value string) bool {
+	if m.regexp != nil {
+		return m.regexp.MatchString(value)
+	}
+	return m.value == value
+}
+
+// Template is an infra agent integrations config whose ${discovery.*} placeholders are replaced with the variables
+// of each item discovered matching it.
+type Template struct {
+	matchers     []matcher
+	integrations []byte
+}
+
+// RenderedItem is the integrations config rendered for a discovered item.
+type RenderedItem struct {
+	DiscoveryID string
+	// Mismatch is the match variable the item does not match, if any. No config is rendered for unmatched items.
+	Mismatch string
+	// Unresolved lists the placeholders whose variable the item does not have. The agent does not run the
+	// integrations of items with unresolved placeholders.
+	Unresolved []string
+	Config     []byte
+}
+
+// ParseTemplate parses an infra agent integrations config using discovery.
+func ParseTemplate(data []byte) (*Template, error) {
+	var file templateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	if len(file.Integrations) == 0 {
+		return nil, ErrTemplateWithoutIntegrations
+	}
+
+	integrations, err := yaml.Marshal(map[string]interface{}{"integrations": file.Integrations})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling template integrations: %w", err)
+	}
+
+	t := &Template{integrations: integrations}
+	for variable, value := range file.Discovery.Command.Match {
+		m := matcher{variable: variable, value: fmt.Sprint(value)}
+		if len(m.value) > 1 && strings.HasPrefix(m.value, "/") && strings.HasSuffix(m.value, "/") {
+			if m.regexp, err = regexp.Compile(m.value[1 : len(m.value)-1]); err != nil {
+				return nil, fmt.Errorf("parsing match of variable %q: %w", variable, err)
+			}
+		}
+		t.matchers = append(t.matchers, m)
+	}
+	sort.Slice(t.matchers, func(i, j int) bool { return t.matchers[i].variable < t.matchers[j].variable })
+
+	return t, nil
+}
+
+// Render renders the integrations config for each item of the output, as the infra agent does.
+func (t *Template) Render(output Output) []RenderedItem {
+	rendered := make([]RenderedItem, 0, len(output))
+
+	for _, item := range output {
+		values := flattenVariables(item.Variables)
+		result := RenderedItem{DiscoveryID: values[discoveryID]}
+
+		for _, m := range t.matchers {
+			if value, found := values[m.variable]; !found || !m.matches(value) {
+				result.Mismatch = m.variable
+				break
+			}
+		}
+		if result.Mismatch != "" {
+			rendered = append(rendered, result)
+			continue
+		}
+
+		unresolved := map[string]bool{}
+		result.Config = discoveryPlaceholder.ReplaceAllFunc(t.integrations, func(placeholder []byte) []byte {
+			variable := string(discoveryPlaceholder.FindSubmatch(placeholder)[1])
+			value, found := values[variable]
+			if !found {
+				unresolved[variable] = true
+				return placeholder
+			}
+			return []byte(value)
+		})
+
+		for variable := range unresolved {
+			result.Unresolved = append(result.Unresolved, variable)
+		}
+		sort.Strings(result.Unresolved)
+
+		rendered = append(rendered, result)
+	}
+
+	return rendered
+}
+
+// flattenVariables returns the variables as the infra agent reads them, with nested values named by their path,
+// e.g. ports.http or externalIPs.0.
+func flattenVariables(variables VariablesMap) map[string]string {
+	flattened := map[string]string{}
+
+	// Variables are marshaled to read them the same way whether they were just discovered or read from a file.
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return flattened
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if decoder.Decode(&values) != nil {
+		return flattened
+	}
+
+	for name, value := range values {
+		flatten(name, value, flattened)
+	}
+	return flattened
+}
+
+func flatten(name string, value interface{}, into map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			flatten(name+"."+k, nested, into)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			flatten(name+"."+strconv.Itoa(i), nested, into)
+		}
+	case nil:
+	default:
+		into[name] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `
+discovery:
+  command:
+    exec: /var/db/newrelic-infra/nri-discovery-kubernetes
+    match:
+      label.app: /^nginx/
+      namespace: default
+integrations:
+  - name: nri-nginx
+    env:
+      STATUS_URL: http://${discovery.ip}:${discovery.ports.http}/status
+      REMOTE_MONITORING: true
+    labels:
+      env: ${config.env}
+`
+
+func templateItem(pod, app string) DiscoveredItem {
+	item := containerItem(pod, "nginx", "nginx:1.25")
+	item.Variables[discoveryID] = pod
+	item.Variables[ip] = "10.1.0.1"
+	item.Variables[labelPrefix+"app"] = app
+	return item
+}
+
+func TestTemplate_Render(t *testing.T) {
+	template, err := ParseTemplate([]byte(testTemplate))
+	require.NoError(t, err)
+
+	missingPort := templateItem("missing-port", "nginx")
+	missingPort.Variables[ports] = kubernetes.PortsMap{"metrics": 9113}
+
+	rendered := template.Render(Output{
+		templateItem("nginx", "nginx-ingress"),
+		templateItem("redis", "redis"),
+		missingPort,
+	})
+	require.Len(t, rendered, 3)
+
+	assert.Equal(t, "nginx", rendered[0].DiscoveryID)
+	assert.Empty(t, rendered[0].Mismatch)
+	assert.Empty(t, rendered[0].Unresolved)
+	assert.Contains(t, string(rendered[0].Config), "STATUS_URL: http://10.1.0.1:8080/status")
+	assert.Contains(t, string(rendered[0].Config), "${config.env}", "Placeholders other than discovery ones are left to the agent")
+
+	assert.Equal(t, "label.app", rendered[1].Mismatch)
+	assert.Nil(t, rendered[1].Config, "Unmatched items are not rendered")
+
+	assert.Equal(t, []string{"ports.http"}, rendered[2].Unresolved)
+	assert.Contains(t, string(rendered[2].Config), "${discovery.ports.http}")
+}
+
+func TestTemplate_RenderFromFile(t *testing.T) {
+	template, err := ParseTemplate([]byte(testTemplate))
+	require.NoError(t, err)
+
+	// Variables read back from a JSON output are rendered the same way as the ones discovered.
+	data, err := json.Marshal(Output{templateItem("nginx", "nginx")})
+	require.NoError(t, err)
+	var output Output
+	require.NoError(t, json.Unmarshal(data, &output))
+
+	rendered := template.Render(output)
+	require.Len(t, rendered, 1)
+	assert.Contains(t, string(rendered[0].Config), "STATUS_URL: http://10.1.0.1:8080/status")
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	_, err := ParseTemplate([]byte("discovery: {}"))
+	assert.ErrorIs(t, err, ErrTemplateWithoutIntegrations)
+
+	_, err = ParseTemplate([]byte("discovery:\n  command:\n    match:\n      image: /[/\nintegrations:\n  - name: nri-redis\n"))
+	assert.Error(t, err)
+
+	_, err = ParseTemplate([]byte("integrations: ["))
+	assert.Error(t, err)
+}
+
+func TestFlattenVariables(t *testing.T) {
+	flattened := flattenVariables(VariablesMap{
+		name:        "redis",
+		ports:       kubernetes.PortsMap{"0": 6379, "redis": 6379},
+		externalIPs: []string{"1.2.3.4"},
+		"ready":     true,
+	})
+
+	assert.Equal(t, map[string]string{
+		"name":          "redis",
+		"ports.0":       "6379",
+		"ports.redis":   "6379",
+		"externalIPs.0": "1.2.3.4",
+		"ready":         "true",
+	}, flattened)
+}
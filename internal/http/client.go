@@ -7,8 +7,9 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
-	"github.com/sethgrid/pester"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,7 +20,8 @@ const (
 )
 
 var (
-	ErrNoConnector = errors.New("connector should not be nil")
+	ErrNoConnector    = errors.New("connector should not be nil")
+	ErrUnknownBackoff = errors.New("unknown backoff strategy")
 )
 
 // Client implements a client for Kubelet, capable of retrieving prometheus metrics from a given endpoint.
@@ -27,7 +29,7 @@ type Client struct {
 	logger   *log.Logger
 	doer     Doer
 	endpoint url.URL
-	retries  int
+	policy   retry.Policy
 }
 
 type OptionFunc func(kc *Client) error
@@ -40,22 +42,35 @@ func WithLogger(logger *log.Logger) OptionFunc {
 	}
 }
 
-// WithMaxRetries returns an OptionFunc to change the number of retries used int Pester Client.
+// WithMaxRetries returns an OptionFunc to change the number of retries of failed requests.
 func WithMaxRetries(retries int) OptionFunc {
 	return func(kubeletClient *Client) error {
-		kubeletClient.retries = retries
+		kubeletClient.policy.MaxRetries = retries
 		return nil
 	}
 }
 
-// NewClient builds a Client using the given options.
-func NewClient(connector Connector, opts ...OptionFunc) (*Client, error) {
+// WithBackoff returns an OptionFunc to change the backoff strategy, linear by default, and the maximum wait between retries.
+func WithBackoff(backoff string, maxBackoff time.Duration) OptionFunc {
+	return func(kubeletClient *Client) error {
+		if !retry.IsValidBackoff(backoff) {
+			return fmt.Errorf("%w: %q", ErrUnknownBackoff, backoff)
+		}
+		kubeletClient.policy.Backoff = backoff
+		kubeletClient.policy.MaxBackoff = maxBackoff
+		return nil
+	}
+}
+
+// NewClient builds a Client using the given options, connecting through the connector within the given context.
+func NewClient(ctx context.Context, connector Connector, opts ...OptionFunc) (*Client, error) {
 	if connector == nil {
 		return nil, ErrNoConnector
 	}
 
 	c := &Client{
 		logger: log.New(),
+		policy: retry.Policy{Backoff: retry.BackoffLinear},
 	}
 
 	for i, opt := range opts {
@@ -64,21 +79,19 @@ func NewClient(connector Connector, opts ...OptionFunc) (*Client, error) {
 		}
 	}
 
-	conn, err := connector.Connect()
+	conn, err := connector.Connect(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to kubelet using the connector: %w", err)
 	}
 
-	if client, ok := conn.client.(*http.Client); ok {
-		httpPester := pester.NewExtendedClient(client)
-		httpPester.Backoff = pester.LinearBackoff
-		httpPester.MaxRetries = c.retries
-		httpPester.LogHook = func(e pester.ErrEntry) {
-			c.logger.Debugf("getting data from kubelet: %v", e)
+	if _, ok := conn.client.(*http.Client); ok {
+		c.doer = &retryingDoer{
+			doer:   conn.client,
+			policy: c.policy,
+			logger: c.logger,
 		}
-		c.doer = httpPester
 	} else {
-		c.logger.Debugf("running kubelet client without pester")
+		c.logger.Debugf("running kubelet client without retries")
 		c.doer = conn.client
 	}
 
@@ -88,12 +101,13 @@ func NewClient(connector Connector, opts ...OptionFunc) (*Client, error) {
 }
 
 // Get implements Getter interface by sending GET request using configured client.
-func (c *Client) Get(urlPath string) (*http.Response, error) {
+// Retries are stopped as soon as the context is done.
+func (c *Client) Get(ctx context.Context, urlPath string) (*http.Response, error) {
 	// Notice that this is the client to interact with kubelet. In case of CAdvisor the MetricFamiliesGetFunc is used
 	e := c.endpoint
 	e.Path = path.Join(c.endpoint.Path, urlPath)
 
-	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, e.String(), nil)
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, e.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request to: %s. Got error: %w ", e.String(), err)
 	}
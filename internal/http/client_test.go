@@ -1,6 +1,9 @@
 package http_test
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"net/http"
@@ -45,6 +48,7 @@ func TestClientCalls(t *testing.T) {
 	k8sClient, cf, inClusterConfig, logger := getTestData(s)
 
 	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -52,7 +56,7 @@ func TestClientCalls(t *testing.T) {
 
 	require.NoError(t, err, "Client creation succeeded")
 
-	r, err := kubeletClient.Get(kubeletMetric)
+	r, err := kubeletClient.Get(context.Background(), kubeletMetric)
 	require.NoError(t, err, "Client did the request without errors")
 	defer r.Body.Close()
 
@@ -78,6 +82,7 @@ func TestClientCallsViaAPIProxy(t *testing.T) {
 	cf.Host = "invalid" // disabling local connection
 
 	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -85,7 +90,7 @@ func TestClientCallsViaAPIProxy(t *testing.T) {
 
 	require.NoError(t, err, "Client creation succeeded")
 
-	r, err := kubeletClient.Get(kubeletMetric)
+	r, err := kubeletClient.Get(context.Background(), kubeletMetric)
 	require.NoError(t, err, "Client did the request without errors")
 	defer r.Body.Close()
 
@@ -117,6 +122,7 @@ func TestConfigPrecedence(t *testing.T) {
 	cf.Port = port
 
 	_, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -134,6 +140,7 @@ func TestClientFailingProbingHTTP(t *testing.T) {
 	c, cf, inClusterConfig, logger := getTestData(s)
 
 	_, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -167,6 +174,7 @@ func TestClientFailingProbingHTTPS(t *testing.T) {
 	cf.TLS = true
 
 	_, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -221,13 +229,14 @@ func TestClientTimeoutAndRetries(t *testing.T) {
 	cf.Timeout = timeout
 
 	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(2),
 	)
 
 	require.NoError(t, err)
 
-	r, err := kubeletClient.Get(kubeletMetricWithDelay)
+	r, err := kubeletClient.Get(context.Background(), kubeletMetricWithDelay)
 	require.NoError(t, err, "Client created correctly")
 	defer r.Body.Close()
 
@@ -235,6 +244,191 @@ func TestClientTimeoutAndRetries(t *testing.T) {
 	assert.Equal(t, 2, delayedRequests, "Client did a successful second retry")
 }
 
+func TestClientGetCancelledByContext(t *testing.T) {
+	t.Parallel()
+
+	timeout := 5000
+	release := make(chan struct{})
+
+	s := httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			if r.RequestURI == kubeletMetricWithDelay {
+				<-release
+			}
+			rw.WriteHeader(200)
+		},
+	))
+	defer close(release)
+
+	c, cf, inClusterConfig, logger := getTestData(s)
+	cf.Timeout = timeout
+
+	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
+		internalhttp.WithMaxRetries(retries),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = kubeletClient.Get(ctx, kubeletMetricWithDelay)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Client request is cancelled by the context")
+	assert.Less(t, time.Since(start), time.Duration(timeout)*time.Millisecond, "Client neither waits for the timeout nor retries")
+}
+
+func TestClientRetryClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		status       int
+		header       http.Header
+		wantStatus   int
+		wantRequests int
+	}{
+		{
+			name:         "unauthorized_not_retried",
+			status:       http.StatusUnauthorized,
+			wantStatus:   http.StatusUnauthorized,
+			wantRequests: 1,
+		},
+		{
+			name:         "forbidden_not_retried",
+			status:       http.StatusForbidden,
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name:         "service_unavailable_honors_retry_after",
+			status:       http.StatusServiceUnavailable,
+			header:       http.Header{"Retry-After": []string{"0"}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		{
+			name:         "too_many_requests_honors_retry_after",
+			status:       http.StatusTooManyRequests,
+			header:       http.Header{"Retry-After": []string{"0"}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		{
+			name:         "server_error_retried",
+			status:       http.StatusInternalServerError,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := &sync.Mutex{}
+			var requests int
+
+			s := httptest.NewServer(http.HandlerFunc(
+				func(rw http.ResponseWriter, r *http.Request) {
+					if r.RequestURI != kubeletMetric {
+						rw.WriteHeader(http.StatusOK)
+						return
+					}
+
+					l.Lock()
+					requests++
+					first := requests == 1
+					l.Unlock()
+
+					if first {
+						for k, v := range test.header {
+							rw.Header()[k] = v
+						}
+						rw.WriteHeader(test.status)
+						return
+					}
+					rw.WriteHeader(http.StatusOK)
+				},
+			))
+			defer s.Close()
+
+			c, cf, inClusterConfig, logger := getTestData(s)
+
+			kubeletClient, err := internalhttp.NewClient(
+				context.Background(),
+				internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
+				internalhttp.WithMaxRetries(retries),
+				internalhttp.WithBackoff("exponential-jitter", 10*time.Millisecond),
+				internalhttp.WithLogger(logger),
+			)
+			require.NoError(t, err)
+
+			r, err := kubeletClient.Get(context.Background(), kubeletMetric)
+			require.NoError(t, err)
+			defer r.Body.Close()
+
+			assert.Equal(t, test.wantStatus, r.StatusCode)
+			assert.Equal(t, test.wantRequests, requests)
+		})
+	}
+}
+
+func TestClientRetriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+
+	s := httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			if r.RequestURI == kubeletMetric {
+				requests++
+				rw.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		},
+	))
+	defer s.Close()
+
+	c, cf, inClusterConfig, logger := getTestData(s)
+
+	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
+		internalhttp.WithMaxRetries(2),
+		internalhttp.WithBackoff("exponential", time.Millisecond),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err)
+
+	r, err := kubeletClient.Get(context.Background(), kubeletMetric)
+	require.NoError(t, err, "Last response is returned once retries are exhausted")
+	defer r.Body.Close()
+
+	assert.Equal(t, http.StatusBadGateway, r.StatusCode)
+	assert.Equal(t, 2, requests, "Client made as many attempts as configured, as pester did")
+}
+
+func TestClientUnknownBackoff(t *testing.T) {
+	t.Parallel()
+
+	l := &sync.Mutex{}
+	s, _ := testHTTPServerWithEndpoints(t, l, []string{healthz})
+
+	c, cf, inClusterConfig, logger := getTestData(s)
+
+	_, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(c, cf, inClusterConfig, logger),
+		internalhttp.WithBackoff("fibonacci", 0),
+	)
+	assert.ErrorIs(t, err, internalhttp.ErrUnknownBackoff)
+}
+
 func TestClientOptions(t *testing.T) {
 	t.Parallel()
 
@@ -245,6 +439,7 @@ func TestClientOptions(t *testing.T) {
 	k8sClient, cf, inClusterConfig, logger := getTestData(s)
 
 	_, err := internalhttp.NewClient(
+		context.Background(),
 		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
 		internalhttp.WithMaxRetries(retries),
 		internalhttp.WithLogger(logger),
@@ -253,6 +448,264 @@ func TestClientOptions(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestClientKubeletCAFile(t *testing.T) {
+	t.Parallel()
+
+	l := &sync.Mutex{}
+
+	s, requests := testHTTPSServerWithEndpoints(t, l, []string{healthz})
+
+	caFile := path.Join(t.TempDir(), "ca.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	tests := []struct {
+		name       string
+		caFile     string
+		serverName string
+		insecure   bool
+		wantErr    bool
+	}{
+		{
+			name:    "kubelet_verified_with_ca_file",
+			caFile:  caFile,
+			wantErr: false,
+		},
+		{
+			name:       "kubelet_verified_with_ca_file_and_server_name",
+			caFile:     caFile,
+			serverName: "example.com",
+			wantErr:    false,
+		},
+		{
+			name:       "kubelet_certificate_not_valid_for_server_name",
+			caFile:     caFile,
+			serverName: "invalid.local",
+			wantErr:    true,
+		},
+		{
+			name:    "kubelet_not_verified_with_cluster_ca",
+			wantErr: true,
+		},
+		{
+			name:     "kubelet_verification_skipped",
+			insecure: true,
+			wantErr:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient, cf, inClusterConfig, logger := getTestData(s)
+			// Neither the local nor the API proxy connection trusts the test server by default.
+			inClusterConfig.TLSClientConfig.Insecure = false
+			cf.TLS = true
+			cf.KubeletCAFile = tt.caFile
+			cf.KubeletServerName = tt.serverName
+			cf.KubeletInsecureSkipVerify = tt.insecure
+
+			_, err := internalhttp.NewClient(
+				context.Background(),
+				internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+				internalhttp.WithMaxRetries(retries),
+				internalhttp.WithLogger(logger),
+			)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			l.Lock()
+			_, foundLocalHealth := requests[healthz]
+			l.Unlock()
+			assert.True(t, foundLocalHealth, "Client hit Health though Local Connector")
+		})
+	}
+}
+
+func TestClientKubeletScheme(t *testing.T) {
+	t.Parallel()
+
+	l := &sync.Mutex{}
+
+	s, requests := testHTTPServerWithEndpoints(t, l, []string{healthz})
+
+	k8sClient, cf, inClusterConfig, logger := getTestData(s)
+	// The scheme would be http otherwise since the test server port is non-standard.
+	cf.KubeletScheme = "https"
+
+	_, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+		internalhttp.WithMaxRetries(retries),
+		internalhttp.WithLogger(logger),
+	)
+	assert.Error(t, err, "Client fails connecting over https to a plain http kubelet")
+
+	l.Lock()
+	_, foundLocalHealth := requests[healthz]
+	_, foundAPIServerHealth := requests[path.Join(apiProxy, healthz)]
+	l.Unlock()
+
+	assert.False(t, foundLocalHealth, "Client did not fall back to plain http locally")
+	assert.True(t, foundAPIServerHealth, "Client hit Health though API Server proxy")
+}
+
+func TestClientConnectionCache(t *testing.T) {
+	t.Parallel()
+
+	l := &sync.Mutex{}
+
+	s, requests := testHTTPSServerWithEndpoints(t, l, []string{path.Join(apiProxy, healthz), path.Join(apiProxy, kubeletMetric)})
+
+	k8sClient, cf, inClusterConfig, logger := getTestData(s)
+	cf.Host = "invalid" // disabling local connection
+	cf.ConnectionCacheFile = path.Join(t.TempDir(), "connection.json")
+	cf.ConnectionCacheTTL = time.Minute
+
+	_, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err, "Client probed the connection")
+	require.FileExists(t, cf.ConnectionCacheFile, "Client stored the connection found")
+	stored := connectionStoredAt(t, cf.ConnectionCacheFile)
+
+	l.Lock()
+	delete(requests, path.Join(apiProxy, healthz))
+	l.Unlock()
+
+	// Probing would fail getting the kubelet port from a Node that does not exist.
+	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(fake.NewSimpleClientset(), cf, inClusterConfig, logger),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err, "Client used the cached connection")
+	assert.True(t, connectionStoredAt(t, cf.ConnectionCacheFile).After(stored), "Reusing the cached connection refreshes it")
+
+	r, err := kubeletClient.Get(context.Background(), kubeletMetric)
+	require.NoError(t, err)
+	defer r.Body.Close()
+
+	l.Lock()
+	_, foundAPIServerHealth := requests[path.Join(apiProxy, healthz)]
+	_, foundKubelet := requests[path.Join(apiProxy, kubeletMetric)]
+	l.Unlock()
+
+	assert.False(t, foundAPIServerHealth, "Client trusted the cached connection without checking it")
+	assert.True(t, foundKubelet, "Client fetched metrics through the cached connection")
+	assert.FileExists(t, cf.ConnectionCacheFile)
+}
+
+func TestClientConnectionCacheFailing(t *testing.T) {
+	t.Parallel()
+
+	l := &sync.Mutex{}
+
+	s, _ := testHTTPServerWithEndpoints(t, l, []string{healthz})
+
+	k8sClient, cf, inClusterConfig, logger := getTestData(s)
+	cf.ConnectionCacheFile = path.Join(t.TempDir(), "connection.json")
+	cf.ConnectionCacheTTL = time.Minute
+
+	_, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err)
+
+	kubeletClient, err := internalhttp.NewClient(
+		context.Background(),
+		internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+		internalhttp.WithLogger(logger),
+	)
+	require.NoError(t, err, "Client used the cached connection")
+
+	// The endpoint is not served, so the request fails.
+	r, err := kubeletClient.Get(context.Background(), kubeletMetric)
+	require.NoError(t, err)
+	defer r.Body.Close()
+
+	assert.NoFileExists(t, cf.ConnectionCacheFile, "The failing connection is probed again on the next run")
+}
+
+func connectionStoredAt(t *testing.T, file string) time.Time {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var cached struct {
+		StoredAt time.Time `json:"storedAt"`
+	}
+	require.NoError(t, json.Unmarshal(data, &cached))
+
+	return cached.StoredAt
+}
+
+func TestClientConnectionCacheNotUsed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		modify func(cf *config.Config)
+	}{
+		{
+			name:   "expired",
+			ttl:    time.Nanosecond,
+			modify: func(cf *config.Config) {},
+		},
+		{
+			name: "configuration_changed",
+			ttl:  time.Minute,
+			modify: func(cf *config.Config) {
+				cf.NodeName = "other-node"
+			},
+		},
+		{
+			name: "kubelet_tls_changed",
+			ttl:  time.Minute,
+			modify: func(cf *config.Config) {
+				cf.KubeletCAFile = "other-ca.crt"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &sync.Mutex{}
+
+			s, _ := testHTTPServerWithEndpoints(t, l, []string{healthz})
+
+			k8sClient, cf, inClusterConfig, logger := getTestData(s)
+			cf.ConnectionCacheFile = path.Join(t.TempDir(), "connection.json")
+			cf.ConnectionCacheTTL = tt.ttl
+
+			_, err := internalhttp.NewClient(
+				context.Background(),
+				internalhttp.DefaultConnector(k8sClient, cf, inClusterConfig, logger),
+				internalhttp.WithLogger(logger),
+			)
+			require.NoError(t, err)
+
+			tt.modify(cf)
+
+			// Probing fails getting the kubelet port from a Node that does not exist.
+			_, err = internalhttp.NewClient(
+				context.Background(),
+				internalhttp.DefaultConnector(fake.NewSimpleClientset(), cf, inClusterConfig, logger),
+				internalhttp.WithLogger(logger),
+			)
+			assert.Error(t, err, "Client probed the connection again")
+		})
+	}
+}
+
 func getTestData(s *httptest.Server) (*fake.Clientset, *config.Config, *rest.Config, *log.Logger) {
 	u, _ := url.Parse(s.URL)
 	port, _ := strconv.Atoi(u.Port())
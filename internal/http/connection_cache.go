@@ -0,0 +1,153 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
+	"k8s.io/client-go/rest"
+)
+
+type connectionMode string
+
+const (
+	connectionModeLocal    connectionMode = "local"
+	connectionModeAPIProxy connectionMode = "apiProxy"
+)
+
+var (
+	ErrCachedConnectionExpired  = errors.New("cached connection expired")
+	ErrCachedConnectionMismatch = errors.New("cached connection was stored with a different configuration")
+)
+
+// cachedConnection is the connection stored in the connection cache file.
+type cachedConnection struct {
+	// Key identifies the configuration used to find the connection, so changes invalidate it.
+	Key      string         `json:"key"`
+	Mode     connectionMode `json:"mode"`
+	Scheme   string         `json:"scheme"`
+	HostURL  string         `json:"hostURL"`
+	StoredAt time.Time      `json:"storedAt"`
+}
+
+// cacheKey identifies the options the connection depends on, including the kubelet TLS ones since a connection
+// verified with other certificates must not be reused.
+func (dp *defaultConnector) cacheKey() string {
+	c := dp.config
+	return fmt.Sprintf("%s|%d|%s|%t|%s|%s|%s|%s|%s|%s|%t", c.Host, c.Port, c.NodeName, c.TLS, c.KubeletScheme, dp.inClusterConfig.Host,
+		c.KubeletCAFile, c.KubeletClientCert, c.KubeletClientKey, c.KubeletServerName, c.KubeletInsecureSkipVerify)
+}
+
+// connectCached returns the connection stored by a previous run, if it has not expired, without checking it.
+// The connection is trusted until it expires or fails, refreshing when it was stored each time it is reused.
+func (dp *defaultConnector) connectCached() (*connParams, error) {
+	data, err := os.ReadFile(dp.config.ConnectionCacheFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading connection cache: %w", err)
+	}
+
+	cached := cachedConnection{}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("parsing connection cache: %w", err)
+	}
+
+	if cached.Key != dp.cacheKey() {
+		return nil, ErrCachedConnectionMismatch
+	}
+
+	if time.Since(cached.StoredAt) > dp.config.ConnectionCacheTTL {
+		return nil, ErrCachedConnectionExpired
+	}
+
+	dp.logger.Debugf("Using cached kubelet connection mode=%q scheme=%q hostURL=%q", cached.Mode, cached.Scheme, cached.HostURL)
+
+	var conn connParams
+	switch {
+	case cached.Mode == connectionModeAPIProxy:
+		tripperAPI, err := rest.TransportFor(dp.inClusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("creating tripper connecting to kubelet through API server proxy: %w", err)
+		}
+		conn, err = dp.connParamsAPIProxy(dp.inClusterConfig.Host, dp.config.NodeName, tripperAPI)
+		if err != nil {
+			return nil, err
+		}
+	case cached.Mode == connectionModeLocal && cached.Scheme == httpsScheme:
+		trip, err := tripperWithBearerTokenAndRefresh(dp.inClusterConfig.BearerTokenFile, dp.inClusterConfig, dp.config)
+		if err != nil {
+			return nil, fmt.Errorf("creating tripper connecting to kubelet through nodeIP: %w", err)
+		}
+		conn = dp.defaultConnParamsHTTPS(cached.HostURL, trip)
+	case cached.Mode == connectionModeLocal && cached.Scheme == httpScheme:
+		conn = dp.defaultConnParamsHTTP(cached.HostURL)
+	default:
+		return nil, fmt.Errorf("unknown cached connection mode=%q scheme=%q", cached.Mode, cached.Scheme)
+	}
+
+	if client, ok := conn.client.(*http.Client); ok {
+		client.Transport = &invalidatingTripper{next: client.Transport, invalidate: dp.invalidateConnection}
+	}
+
+	cached.StoredAt = time.Now()
+	dp.writeConnection(cached)
+
+	return &conn, nil
+}
+
+// storeConnection persists the connection found. Failures are only logged since caching is an optimization.
+func (dp *defaultConnector) storeConnection(conn *connParams, mode connectionMode) {
+	dp.writeConnection(cachedConnection{
+		Key:      dp.cacheKey(),
+		Mode:     mode,
+		Scheme:   conn.url.Scheme,
+		HostURL:  conn.url.Host,
+		StoredAt: time.Now(),
+	})
+}
+
+func (dp *defaultConnector) writeConnection(cached cachedConnection) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		dp.logger.Warnf("Marshaling kubelet connection to cache: %v", err)
+		return
+	}
+
+	if err := utils.WriteFileAtomic(dp.config.ConnectionCacheFile, data, 0o600); err != nil {
+		dp.logger.Warnf("Storing kubelet connection in %q: %v", dp.config.ConnectionCacheFile, err)
+	}
+}
+
+// invalidateConnection removes the cached connection, so the next run probes the kubelet connection again.
+func (dp *defaultConnector) invalidateConnection() {
+	if err := os.Remove(dp.config.ConnectionCacheFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		dp.logger.Warnf("Removing kubelet connection from %q: %v", dp.config.ConnectionCacheFile, err)
+	}
+}
+
+// invalidatingTripper invalidates the cached connection it is used for once a request through it fails, either
+// because the kubelet cannot be reached or answers with a non-200 status, as checking the connection does.
+type invalidatingTripper struct {
+	next       http.RoundTripper
+	invalidate func()
+	once       sync.Once
+}
+
+func (t *invalidatingTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(r)
+	// Requests cancelled by the run do not tell whether the connection works.
+	if r.Context().Err() == nil && (err != nil || resp.StatusCode != http.StatusOK) {
+		t.once.Do(t.invalidate)
+	}
+
+	return resp, err
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -30,7 +31,7 @@ var (
 
 // Connector provides an interface to retrieve connParams to connect to a Kubelet instance.
 type Connector interface {
-	Connect() (*connParams, error)
+	Connect(ctx context.Context) (*connParams, error)
 }
 
 type defaultConnector struct {
@@ -56,25 +57,49 @@ func DefaultConnector(kc kubernetes.Interface, config *config.Config, inClusterC
 // on the other hand passing through api-proxy, authentication is managed by the kubernetes client itself.
 // Notice that we cannot use the as well rest.TransportFor to connect locally since the certificate sent by kubelet,
 // cannot be verified in the same way we do for the apiServer.
-func (dp *defaultConnector) Connect() (*connParams, error) {
-	kubeletPort, err := dp.getPort()
+// When a connection cache file is configured, the connection found by a previous run is used until it expires or fails.
+func (dp *defaultConnector) Connect(ctx context.Context) (*connParams, error) {
+	if dp.config.ConnectionCacheFile == "" {
+		conn, _, err := dp.probe(ctx)
+		return conn, err
+	}
+
+	conn, err := dp.connectCached()
+	if err == nil {
+		return conn, nil
+	}
+	dp.logger.Debugf("Probing kubelet connection since the cached one cannot be used: %v", err)
+
+	conn, mode, err := dp.probe(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("getting kubelet port: %w", err)
+		return nil, err
+	}
+
+	dp.storeConnection(conn, mode)
+
+	return conn, nil
+}
+
+// probe checks the local connection and then the API proxy, returning the first one succeeding and its mode.
+func (dp *defaultConnector) probe(ctx context.Context) (*connParams, connectionMode, error) {
+	kubeletPort, err := dp.getPort(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting kubelet port: %w", err)
 	}
 
 	kubeletScheme := dp.schemeFor(dp.config)
 	hostURL := net.JoinHostPort(dp.config.Host, fmt.Sprint(kubeletPort))
 
 	dp.logger.Infof("Trying to connect to kubelet locally with scheme=%q hostURL=%q", kubeletScheme, hostURL)
-	trip, err := tripperWithBearerTokenAndRefresh(dp.inClusterConfig.BearerTokenFile, dp.inClusterConfig)
+	trip, err := tripperWithBearerTokenAndRefresh(dp.inClusterConfig.BearerTokenFile, dp.inClusterConfig, dp.config)
 	if err != nil {
-		return nil, fmt.Errorf("creating tripper connecting to kubelet through nodeIP: %w", err)
+		return nil, "", fmt.Errorf("creating tripper connecting to kubelet through nodeIP: %w", err)
 	}
 
-	conn, err := dp.checkLocalConnection(trip, kubeletScheme, hostURL)
+	conn, err := dp.checkLocalConnection(ctx, trip, kubeletScheme, hostURL)
 	if err == nil {
 		dp.logger.Infof("Connected to Kubelet through nodeIP with scheme=%q hostURL=%q", kubeletScheme, hostURL)
-		return conn, nil
+		return conn, connectionModeLocal, nil
 	}
 	dp.logger.Warnf("Kubelet not reachable locally with scheme=%q hostURL=%q: %v", kubeletScheme, hostURL, err)
 	dp.logger.Warnf("this could lead to interrogate the API Server too much to an extent the cluster may suffer. Fix you configuration!")
@@ -82,40 +107,40 @@ func (dp *defaultConnector) Connect() (*connParams, error) {
 	dp.logger.Infof("Trying to connect to kubelet through API proxy %q to node %q", dp.inClusterConfig.Host, dp.config.NodeName)
 	tripperAPI, err := rest.TransportFor(dp.inClusterConfig)
 	if err != nil {
-		return nil, fmt.Errorf("creating tripper connecting to kubelet through API server proxy: %w", err)
+		return nil, "", fmt.Errorf("creating tripper connecting to kubelet through API server proxy: %w", err)
 	}
 
-	conn, err = dp.checkConnectionAPIProxy(dp.inClusterConfig.Host, dp.config.NodeName, tripperAPI)
+	conn, err = dp.checkConnectionAPIProxy(ctx, dp.inClusterConfig.Host, dp.config.NodeName, tripperAPI)
 	if err != nil {
-		return nil, fmt.Errorf("creating connection parameters for API proxy: %w", err)
+		return nil, "", fmt.Errorf("creating connection parameters for API proxy: %w", err)
 	}
 
-	return conn, nil
+	return conn, connectionModeAPIProxy, nil
 }
 
-func (dp *defaultConnector) checkLocalConnection(tripperWithBearerTokenRefreshing http.RoundTripper, scheme string, hostURL string) (*connParams, error) {
+func (dp *defaultConnector) checkLocalConnection(ctx context.Context, tripperWithBearerTokenRefreshing http.RoundTripper, scheme string, hostURL string) (*connParams, error) {
 	dp.logger.Debugf("connecting to kubelet directly with nodeIP")
 	var err error
 	var conn *connParams
 
 	switch scheme {
 	case httpScheme:
-		if conn, err = dp.checkConnectionHTTP(hostURL); err == nil {
+		if conn, err = dp.checkConnectionHTTP(ctx, hostURL); err == nil {
 			return conn, nil
 		}
 	case httpsScheme:
-		if conn, err = dp.checkConnectionHTTPS(hostURL, tripperWithBearerTokenRefreshing); err == nil {
+		if conn, err = dp.checkConnectionHTTPS(ctx, hostURL, tripperWithBearerTokenRefreshing); err == nil {
 			return conn, nil
 		}
 	default:
 		dp.logger.Infof("Checking both HTTP and HTTPS since the scheme was not detected automatically, " +
-			"you can set set kubelet.scheme to avoid this behaviour") // nolint: misspell // collision between spelling actions and American/GB English...
+			"you can set --" + config.FlagKubeletScheme + " to avoid this behaviour") // nolint: misspell // collision between spelling actions and American/GB English...
 
-		if conn, err = dp.checkConnectionHTTPS(hostURL, tripperWithBearerTokenRefreshing); err == nil {
+		if conn, err = dp.checkConnectionHTTPS(ctx, hostURL, tripperWithBearerTokenRefreshing); err == nil {
 			return conn, nil
 		}
 
-		if conn, err = dp.checkConnectionHTTP(hostURL); err == nil {
+		if conn, err = dp.checkConnectionHTTP(ctx, hostURL); err == nil {
 			return conn, nil
 		}
 	}
@@ -123,14 +148,14 @@ func (dp *defaultConnector) checkLocalConnection(tripperWithBearerTokenRefreshin
 	return nil, fmt.Errorf("no connection succeeded through localhost: %w", err)
 }
 
-func (dp *defaultConnector) getPort() (int32, error) {
+func (dp *defaultConnector) getPort(ctx context.Context) (int32, error) {
 	if dp.config.Port != 0 {
 		dp.logger.Debugf("Setting Port %d as specified by user config", dp.config.Port)
 		return int32(dp.config.Port), nil
 	}
 
 	// We pay the price of a single call getting a node to avoid asking the user the Kubelet port.
-	node, err := dp.kc.CoreV1().Nodes().Get(context.Background(), dp.config.NodeName, metav1.GetOptions{})
+	node, err := dp.kc.CoreV1().Nodes().Get(ctx, dp.config.NodeName, metav1.GetOptions{})
 	if err != nil {
 		return 0, fmt.Errorf("getting node %q: %w", dp.config.NodeName, err)
 	}
@@ -142,6 +167,11 @@ func (dp *defaultConnector) getPort() (int32, error) {
 }
 
 func (dp *defaultConnector) schemeFor(c *config.Config) string {
+	if c.KubeletScheme != "" {
+		dp.logger.Debugf("kubelet scheme explicitly set. using schema %s", c.KubeletScheme)
+		return c.KubeletScheme
+	}
+
 	if c.TLS {
 		dp.logger.Debugf("flag for TLS explicitly set. using schema %s", httpsScheme)
 		return httpsScheme
@@ -156,7 +186,7 @@ func (dp *defaultConnector) schemeFor(c *config.Config) string {
 		return httpsScheme
 	}
 
-	dp.logger.Warningf("cannot automatically figure out scheme from non-standard port %d, and no TLS flag has been provided. Defaulting to %s", c.Port, httpScheme)
+	dp.logger.Warningf("cannot automatically figure out scheme from non-standard port %d, and neither the TLS nor the %s flag has been provided. Defaulting to %s", c.Port, config.FlagKubeletScheme, httpScheme)
 	return httpScheme
 }
 
@@ -165,13 +195,28 @@ type connParams struct {
 	client Doer
 }
 
-func (dp *defaultConnector) checkConnectionAPIProxy(apiServer string, nodeName string, tripperAPIproxy http.RoundTripper) (*connParams, error) {
+func (dp *defaultConnector) checkConnectionAPIProxy(ctx context.Context, apiServer string, nodeName string, tripperAPIproxy http.RoundTripper) (*connParams, error) {
+	conn, err := dp.connParamsAPIProxy(apiServer, nodeName, tripperAPIproxy)
+	if err != nil {
+		return nil, err
+	}
+
+	dp.logger.Debugf("Testing kubelet connection through API proxy: %s%s", conn.url.Host, conn.url.Path)
+
+	if err = checkConnection(ctx, conn); err != nil {
+		return nil, fmt.Errorf("checking connection via API proxy: %w", err)
+	}
+
+	return &conn, nil
+}
+
+func (dp *defaultConnector) connParamsAPIProxy(apiServer string, nodeName string, tripperAPIproxy http.RoundTripper) (connParams, error) {
 	apiURL, err := url.Parse(apiServer)
 	if err != nil {
-		return nil, fmt.Errorf("parsing kubernetes api url from in cluster config: %w", err)
+		return connParams{}, fmt.Errorf("parsing kubernetes api url from in cluster config: %w", err)
 	}
 
-	conn := connParams{
+	return connParams{
 		client: &http.Client{
 			Timeout:   time.Duration(dp.config.Timeout) * time.Millisecond,
 			Transport: tripperAPIproxy,
@@ -181,43 +226,35 @@ func (dp *defaultConnector) checkConnectionAPIProxy(apiServer string, nodeName s
 			Scheme: apiURL.Scheme,
 			Path:   path.Join(fmt.Sprintf(apiProxyPath, nodeName)),
 		},
-	}
-
-	dp.logger.Debugf("Testing kubelet connection through API proxy: %s%s", apiURL.Host, conn.url.Path)
-
-	if err = checkConnection(conn); err != nil {
-		return nil, fmt.Errorf("checking connection via API proxy: %w", err)
-	}
-
-	return &conn, nil
+	}, nil
 }
 
-func (dp *defaultConnector) checkConnectionHTTP(hostURL string) (*connParams, error) {
+func (dp *defaultConnector) checkConnectionHTTP(ctx context.Context, hostURL string) (*connParams, error) {
 	dp.logger.Debugf("testing kubelet connection over plain http to %s", hostURL)
 
 	conn := dp.defaultConnParamsHTTP(hostURL)
-	if err := checkConnection(conn); err != nil {
+	if err := checkConnection(ctx, conn); err != nil {
 		return nil, fmt.Errorf("checking connection via API proxy: %w", err)
 	}
 
 	return &conn, nil
 }
 
-func (dp *defaultConnector) checkConnectionHTTPS(hostURL string, tripperBearerRefreshing http.RoundTripper) (*connParams, error) {
+func (dp *defaultConnector) checkConnectionHTTPS(ctx context.Context, hostURL string, tripperBearerRefreshing http.RoundTripper) (*connParams, error) {
 	dp.logger.Debugf("testing kubelet connection over https to %s", hostURL)
 
 	conn := dp.defaultConnParamsHTTPS(hostURL, tripperBearerRefreshing)
-	if err := checkConnection(conn); err != nil {
+	if err := checkConnection(ctx, conn); err != nil {
 		return nil, fmt.Errorf("checking connection via API proxy: %w", err)
 	}
 
 	return &conn, nil
 }
 
-func checkConnection(conn connParams) error {
+func checkConnection(ctx context.Context, conn connParams) error {
 	conn.url.Path = path.Join(conn.url.Path, healthzPath)
 
-	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, conn.url.String(), nil)
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, conn.url.String(), nil)
 	if err != nil {
 		return fmt.Errorf("creating request to %q: %w", conn.url.String(), err)
 	}
@@ -235,20 +272,17 @@ func checkConnection(conn connParams) error {
 	return nil
 }
 
-func tripperWithBearerTokenAndRefresh(tokenFile string, inClusterConfig *rest.Config) (http.RoundTripper, error) {
+func tripperWithBearerTokenAndRefresh(tokenFile string, inClusterConfig *rest.Config, c *config.Config) (http.RoundTripper, error) {
 	// Here we're using the default http.Transport configuration, but with a modified TLS config.
 	// The DefaultTransport is casted to an http.RoundTripper interface, so we need to convert it back.
 	t := http.DefaultTransport.(*http.Transport).Clone()
 
-	// Build TLS config from in-cluster config which includes the cluster CA certificate.
-	// This verifies the kubelet's certificate chain while allowing hostname mismatch,
-	// since kubelet certs are typically not issued to `localhost`.
-	transportConfig, err := inClusterConfig.TransportConfig()
-	if err == nil {
-		tlsConfig, tlsErr := transport.TLSConfigFor(transportConfig)
-		if tlsErr == nil && tlsConfig != nil {
-			t.TLSClientConfig = tlsConfig
-		}
+	tlsConfig, err := kubeletTLSConfig(inClusterConfig, c)
+	if err != nil {
+		return nil, fmt.Errorf("building kubelet TLS config: %w", err)
+	}
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
 	}
 
 	// Use the default kubernetes Bearer token authentication RoundTripper
@@ -260,6 +294,56 @@ func tripperWithBearerTokenAndRefresh(tokenFile string, inClusterConfig *rest.Co
 	return tripperWithBearerRefreshing, nil
 }
 
+// kubeletTLSConfig builds the TLS config used to verify the kubelet certificate.
+// By default it is built from in-cluster config which includes the cluster CA certificate, while the kubelet options
+// from the config take precedence for clusters where kubelets are issued certificates from a different CA.
+// Errors are only reported if kubelet options are set, otherwise the default TLS config is used.
+func kubeletTLSConfig(inClusterConfig *rest.Config, c *config.Config) (*tls.Config, error) {
+	hasKubeletOptions := c.KubeletCAFile != "" || c.KubeletClientCert != "" || c.KubeletServerName != "" || c.KubeletInsecureSkipVerify
+
+	transportConfig, err := inClusterConfig.TransportConfig()
+	if err != nil {
+		if !hasKubeletOptions {
+			return nil, nil
+		}
+		transportConfig = &transport.Config{}
+	}
+
+	if c.KubeletCAFile != "" {
+		transportConfig.TLS.CAFile = c.KubeletCAFile
+		transportConfig.TLS.CAData = nil
+	}
+
+	if c.KubeletClientCert != "" {
+		transportConfig.TLS.CertFile = c.KubeletClientCert
+		transportConfig.TLS.KeyFile = c.KubeletClientKey
+		transportConfig.TLS.CertData = nil
+		transportConfig.TLS.KeyData = nil
+		transportConfig.TLS.GetCertHolder = nil
+	}
+
+	if c.KubeletServerName != "" {
+		transportConfig.TLS.ServerName = c.KubeletServerName
+	}
+
+	if c.KubeletInsecureSkipVerify {
+		// Root certificates cannot be set along with the insecure flag.
+		transportConfig.TLS.Insecure = true
+		transportConfig.TLS.CAFile = ""
+		transportConfig.TLS.CAData = nil
+	}
+
+	tlsConfig, err := transport.TLSConfigFor(transportConfig)
+	if err != nil {
+		if !hasKubeletOptions {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tlsConfig, nil
+}
+
 func (dp *defaultConnector) defaultConnParamsHTTP(hostURL string) connParams {
 	httpClient := &http.Client{
 		Timeout: time.Duration(dp.config.Timeout) * time.Millisecond,
@@ -292,7 +376,7 @@ type fixedConnector struct {
 }
 
 // Connect return connParams without probing any endpoint.
-func (mc *fixedConnector) Connect() (*connParams, error) {
+func (mc *fixedConnector) Connect(_ context.Context) (*connParams, error) {
 	return &connParams{
 		url:    mc.URL,
 		client: mc.Client,
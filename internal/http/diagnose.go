@@ -0,0 +1,239 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Probes made to connect to the kubelet, in the order the connector tries them.
+const (
+	ProbeHTTPSLocal = "https-local"
+	ProbeHTTPLocal  = "http-local"
+	ProbeAPIProxy   = "api-proxy"
+)
+
+// Outcomes of the verification of the certificate served to a probe.
+const (
+	TLSNotApplicable = "not applicable"
+	TLSVerified      = "verified"
+	TLSSkipped       = "skipped"
+	TLSFailed        = "failed"
+	TLSUnknown       = "unknown"
+)
+
+// Sources of the kubelet port.
+const (
+	PortSourceConfig       = "configuration"
+	PortSourceNodeEndpoint = "node status DaemonEndpoints"
+	PortSourceDefault      = "default, as the node status could not be read"
+)
+
+// Probe is the outcome of checking one of the ways of connecting to the kubelet.
+type Probe struct {
+	Name string
+	URL  string
+	// StatusCode of the response, zero if none was received.
+	StatusCode      int
+	TLSVerification string
+	Latency         time.Duration
+	Err             error
+	// Hint suggests how to fix the failure, if any.
+	Hint string
+}
+
+// Diagnosis explains how the connector would connect to the kubelet.
+type Diagnosis struct {
+	Port       int32
+	PortSource string
+	// PortErr is set if the port could not be found in the node status, in which case the default one is probed.
+	PortErr error
+	// Scheme the connector tries locally, while every probe is made when diagnosing.
+	Scheme       string
+	TokenFile    string
+	TokenPresent bool
+	Probes       []Probe
+}
+
+// Connected checks whether any of the probes succeeded.
+func (d *Diagnosis) Connected() bool {
+	for _, p := range d.Probes {
+		if p.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnose makes every probe the connector tries to connect to the kubelet, instead of stopping at the first
+// one succeeding, reporting the outcome of each of them.
+func Diagnose(ctx context.Context, kc kubernetes.Interface, c *config.Config, inClusterConfig *rest.Config, logger *logrus.Logger) *Diagnosis {
+	dp := &defaultConnector{logger: logger, kc: kc, inClusterConfig: inClusterConfig, config: c}
+
+	d := &Diagnosis{
+		PortSource:   PortSourceConfig,
+		TokenFile:    inClusterConfig.BearerTokenFile,
+		TokenPresent: inClusterConfig.BearerToken != "" || isNonEmptyFile(inClusterConfig.BearerTokenFile),
+	}
+	if c.Port == 0 {
+		d.PortSource = PortSourceNodeEndpoint
+	}
+
+	d.Port, d.PortErr = dp.getPort(ctx)
+	if d.PortErr != nil {
+		// The API proxy does not need the port, while the kubelet is likely listening on the default one.
+		d.Port, d.PortSource = config.DefaultPort, PortSourceDefault
+	}
+
+	d.Scheme = dp.schemeFor(c)
+	hostURL := net.JoinHostPort(c.Host, fmt.Sprint(d.Port))
+
+	trip, err := tripperWithBearerTokenAndRefresh(inClusterConfig.BearerTokenFile, inClusterConfig, c)
+	if err != nil {
+		d.Probes = append(d.Probes, Probe{Name: ProbeHTTPSLocal, Err: err, Hint: "check the kubelet TLS options and the service account token file"})
+	} else {
+		tlsConfig, _ := kubeletTLSConfig(inClusterConfig, c) // nolint: errcheck // as the tripper was built
+		insecure := tlsConfig != nil && tlsConfig.InsecureSkipVerify
+		d.Probes = append(d.Probes, probe(ctx, ProbeHTTPSLocal, dp.defaultConnParamsHTTPS(hostURL, trip), insecure, c))
+	}
+
+	d.Probes = append(d.Probes, probe(ctx, ProbeHTTPLocal, dp.defaultConnParamsHTTP(hostURL), false, c))
+
+	tripperAPI, err := rest.TransportFor(inClusterConfig)
+	if err != nil {
+		d.Probes = append(d.Probes, Probe{Name: ProbeAPIProxy, Err: err, Hint: "check the in-cluster or kubeconfig configuration"})
+		return d
+	}
+	apiProxy, err := dp.connParamsAPIProxy(inClusterConfig.Host, c.NodeName, tripperAPI)
+	if err != nil {
+		d.Probes = append(d.Probes, Probe{Name: ProbeAPIProxy, Err: err, Hint: "check the in-cluster or kubeconfig configuration"})
+		return d
+	}
+	d.Probes = append(d.Probes, probe(ctx, ProbeAPIProxy, apiProxy, inClusterConfig.Insecure, c))
+
+	return d
+}
+
+// probe requests the health endpoint through the connection, as checkConnection does, telling whether the
+// certificate served is verified.
+func probe(ctx context.Context, name string, conn connParams, insecure bool, c *config.Config) Probe {
+	conn.url.Path = path.Join(conn.url.Path, healthzPath)
+	p := Probe{Name: name, URL: conn.url.String(), TLSVerification: TLSNotApplicable}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		p.Err = fmt.Errorf("creating request to %q: %w", p.URL, err)
+		return p
+	}
+
+	start := time.Now()
+	resp, err := conn.client.Do(r)
+	p.Latency = time.Since(start)
+
+	if conn.url.Scheme == httpsScheme {
+		p.TLSVerification = tlsVerification(resp, err, insecure)
+	}
+
+	if err != nil {
+		p.Err = fmt.Errorf("connecting to %q: %w", p.URL, err)
+		p.Hint = hintForError(name, err)
+		return p
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	p.StatusCode = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		p.Err = fmt.Errorf("got non-200 status code: %d. %w", resp.StatusCode, ErrUnexpectedStatusCode)
+		p.Hint = hintForStatus(name, resp.StatusCode, c)
+	}
+
+	return p
+}
+
+func tlsVerification(resp *http.Response, err error, insecure bool) string {
+	switch {
+	case isCertificateError(err):
+		return TLSFailed
+	case insecure:
+		return TLSSkipped
+	case resp != nil && resp.TLS != nil:
+		return TLSVerified
+	default:
+		return TLSUnknown
+	}
+}
+
+func isCertificateError(err error) bool {
+	var (
+		verificationErr *tls.CertificateVerificationError
+		authorityErr    x509.UnknownAuthorityError
+		hostnameErr     x509.HostnameError
+		invalidErr      x509.CertificateInvalidError
+	)
+	return errors.As(err, &verificationErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+func hintForError(name string, err error) string {
+	var hostnameErr x509.HostnameError
+
+	switch {
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("the kubelet certificate is not valid for the host, set --%s to a name it is valid for", config.FlagKubeletServerName)
+	case isCertificateError(err):
+		return fmt.Sprintf("the kubelet certificate is not signed by a trusted CA, set --%s with the CA issuing kubelet certificates, or --%s",
+			config.FlagKubeletCAFile, config.FlagKubeletInsecureSkipVerify)
+	case strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return fmt.Sprintf("the kubelet serves plain HTTP on this port, set --%s=http", config.FlagKubeletScheme)
+	case strings.Contains(err.Error(), "malformed HTTP response"):
+		return fmt.Sprintf("the kubelet serves HTTPS on this port, set --%s=https", config.FlagKubeletScheme)
+	case errors.Is(err, context.DeadlineExceeded) || isTimeout(err):
+		return fmt.Sprintf("the request timed out, check network policies allow the connection or raise --%s", config.FlagTimeout)
+	case name == ProbeAPIProxy:
+		return "the API server is not reachable, check the in-cluster or kubeconfig configuration"
+	default:
+		return fmt.Sprintf("the kubelet is not reachable, check --%s and --%s, and that the pod runs with hostNetwork", config.FlagHost, config.FlagPort)
+	}
+}
+
+func hintForStatus(name string, statusCode int, c *config.Config) string {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return "the service account token was rejected, check it is mounted and not expired"
+	case statusCode == http.StatusForbidden:
+		return "grant get on nodes/proxy to the service account"
+	case statusCode == http.StatusNotFound && name == ProbeAPIProxy:
+		return fmt.Sprintf("node %q was not found, check --%s is set to the name of the node", c.NodeName, config.FlagNodeName)
+	case statusCode == http.StatusBadRequest && name == ProbeHTTPSLocal:
+		return fmt.Sprintf("the kubelet serves plain HTTP on this port, set --%s=http", config.FlagKubeletScheme)
+	case statusCode == http.StatusBadRequest && name == ProbeHTTPLocal:
+		return fmt.Sprintf("the kubelet serves HTTPS on this port, set --%s=https", config.FlagKubeletScheme)
+	default:
+		return "check the kubelet health and logs"
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isNonEmptyFile(file string) bool {
+	if file == "" {
+		return false
+	}
+	info, err := os.Stat(file)
+	return err == nil && info.Size() > 0
+}
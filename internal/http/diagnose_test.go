@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	internalhttp "github.com/newrelic/nri-discovery-kubernetes/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiagnose_HTTP(t *testing.T) {
+	t.Parallel()
+
+	s, _ := testHTTPServerWithEndpoints(t, &sync.Mutex{}, []string{healthz})
+
+	k8sClient, cf, inClusterConfig, logger := getTestData(s)
+
+	d := internalhttp.Diagnose(context.Background(), k8sClient, cf, inClusterConfig, logger)
+	require.NoError(t, d.PortErr)
+	assert.Equal(t, internalhttp.PortSourceNodeEndpoint, d.PortSource)
+	assert.True(t, d.TokenPresent)
+	assert.True(t, d.Connected())
+	require.Len(t, d.Probes, 3, "Every probe is made even if one succeeds")
+
+	httpsLocal, httpLocal, apiProxy := d.Probes[0], d.Probes[1], d.Probes[2]
+
+	assert.Equal(t, internalhttp.ProbeHTTPSLocal, httpsLocal.Name)
+	assert.Error(t, httpsLocal.Err)
+	assert.Contains(t, httpsLocal.Hint, "--kubelet-scheme=http")
+
+	assert.Equal(t, internalhttp.ProbeHTTPLocal, httpLocal.Name)
+	assert.NoError(t, httpLocal.Err)
+	assert.Equal(t, http.StatusOK, httpLocal.StatusCode)
+	assert.Equal(t, internalhttp.TLSNotApplicable, httpLocal.TLSVerification)
+	assert.Empty(t, httpLocal.Hint)
+
+	assert.Equal(t, internalhttp.ProbeAPIProxy, apiProxy.Name)
+	assert.Equal(t, http.StatusNotFound, apiProxy.StatusCode)
+	assert.Contains(t, apiProxy.Hint, `node "test-node" was not found`)
+}
+
+func TestDiagnose_HTTPS(t *testing.T) {
+	t.Parallel()
+
+	s, _ := testHTTPSServerWithEndpoints(t, &sync.Mutex{}, []string{healthz, path.Join(apiProxy, healthz)})
+
+	k8sClient, cf, inClusterConfig, logger := getTestData(s)
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	cf.Port, err = strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	cf.TLS = true
+
+	d := internalhttp.Diagnose(context.Background(), k8sClient, cf, inClusterConfig, logger)
+	require.NoError(t, d.PortErr)
+	assert.Equal(t, internalhttp.PortSourceConfig, d.PortSource)
+	assert.Equal(t, "https", d.Scheme)
+	require.Len(t, d.Probes, 3)
+
+	assert.NoError(t, d.Probes[0].Err)
+	assert.Equal(t, internalhttp.TLSSkipped, d.Probes[0].TLSVerification, "Test in cluster config skips verification")
+
+	assert.Equal(t, http.StatusBadRequest, d.Probes[1].StatusCode)
+	assert.Contains(t, d.Probes[1].Hint, "--kubelet-scheme=https")
+
+	assert.NoError(t, d.Probes[2].Err)
+}
+
+func TestDiagnose_PortNotFound(t *testing.T) {
+	t.Parallel()
+
+	s, _ := testHTTPServerWithEndpoints(t, &sync.Mutex{}, []string{healthz})
+
+	_, cf, inClusterConfig, logger := getTestData(s)
+
+	d := internalhttp.Diagnose(context.Background(), fake.NewSimpleClientset(), cf, inClusterConfig, logger)
+	assert.Error(t, d.PortErr)
+	assert.Equal(t, int32(config.DefaultPort), d.Port)
+	assert.Equal(t, internalhttp.PortSourceDefault, d.PortSource)
+	require.Len(t, d.Probes, 3, "Probes are made on the default port")
+	assert.Equal(t, internalhttp.ProbeAPIProxy, d.Probes[2].Name)
+}
@@ -1,13 +1,14 @@
 package http
 
 import (
+	"context"
 	"net/http"
 )
 
 // Getter is an interface for HTTP client with, which should provide
 // scheme, port and hostname for the HTTP call.
 type Getter interface {
-	Get(path string) (*http.Response, error)
+	Get(ctx context.Context, path string) (*http.Response, error)
 }
 
 type Doer interface {
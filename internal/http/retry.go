@@ -0,0 +1,86 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+// retryingDoer retries failed requests following the retry policy.
+// Requests failing with network errors or 5xx and 429 status codes are retried, while 401 and 403 never are.
+// The Retry-After header is honored on 429 and 503 responses.
+type retryingDoer struct {
+	doer   Doer
+	policy retry.Policy
+	logger *log.Logger
+}
+
+func (rd *retryingDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := rd.doer.Do(req)
+
+		delay, retryable := rd.classify(req, attempt, resp, err)
+		if !retryable || rd.policy.Exhausted(attempt) {
+			return resp, err
+		}
+
+		if err != nil {
+			rd.logger.Debugf("getting data from kubelet, attempt %d: %v", attempt, err)
+		} else {
+			rd.logger.Debugf("getting data from kubelet, attempt %d: got status code %d", attempt, resp.StatusCode)
+			// drain the body before closing it to allow reusing the connection.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close() // nolint: errcheck
+		}
+
+		if waitErr := retry.Wait(req.Context(), delay); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+}
+
+// classify returns whether the request should be retried and the delay before doing so.
+func (rd *retryingDoer) classify(req *http.Request, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		// Requests cancelled through their context are not retried.
+		return rd.policy.Delay(attempt), req.Context().Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return 0, false
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if delay, ok := retryAfter(resp.Header); ok {
+			return rd.policy.Cap(delay), true
+		}
+		return rd.policy.Delay(attempt), true
+	}
+
+	return rd.policy.Delay(attempt), resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter parses the Retry-After header, which holds either the seconds to wait or a date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	signatureHeader   = "X-Discovery-Signature"
+	clusterNameHeader = "X-Discovery-Cluster-Name"
+	nodeNameHeader    = "X-Discovery-Node-Name"
+	signaturePrefix   = "sha256="
+)
+
+// MergeFunc merges an older payload into a newer one, for payloads which do not supersede the older ones,
+// e.g. changes from the previous payload.
+type MergeFunc func(older, newer []byte) ([]byte, error)
+
+// Webhook posts JSON payloads to a URL in the background, retrying failed deliveries following the retry policy.
+// Payloads wait in a bounded queue, where the oldest ones are dropped when full as newer ones supersede them,
+// unless a MergeFunc is set to merge them into the newer one instead.
+type Webhook struct {
+	url         string
+	bearerToken string
+	hmacSecret  []byte
+	clusterName string
+	nodeName    string
+	client      *http.Client
+	policy      retry.Policy
+	merge       MergeFunc
+	logger      *log.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// NewWebhook creates the webhook configured, which is nil if no webhook URL is set.
+func NewWebhook(c *config.Config, logger *log.Logger) *Webhook {
+	if c.WebhookURL == "" {
+		return nil
+	}
+
+	return &Webhook{
+		url:         c.WebhookURL,
+		bearerToken: c.WebhookBearerToken,
+		hmacSecret:  []byte(c.WebhookHMACSecret),
+		clusterName: c.ClusterName,
+		nodeName:    c.NodeName,
+		client:      &http.Client{Timeout: time.Duration(c.Timeout) * time.Millisecond},
+		policy: retry.Policy{
+			MaxRetries: c.Retries,
+			Backoff:    c.Backoff,
+			MaxBackoff: c.MaxBackoff,
+		},
+		logger: logger,
+		queue:  make(chan []byte, c.WebhookQueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// SetMerge sets how payloads queued are merged into the one pushed when the queue is full. It must be set before
+// pushing any payload.
+func (w *Webhook) SetMerge(merge MergeFunc) {
+	w.merge = merge
+}
+
+// Start posts the payloads queued until the webhook is closed. Deliveries in progress are abandoned when the context is done,
+// along with the payloads still queued.
+func (w *Webhook) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(w.done)
+		dropped := 0
+		for payload := range w.queue {
+			if ctx.Err() != nil {
+				dropped++
+				continue
+			}
+			if err := w.post(ctx, payload); err != nil {
+				w.logger.Warnf("posting to webhook: %v", err)
+			}
+		}
+		if dropped > 0 {
+			w.logger.Warnf("webhook stopped, dropping %d payloads not posted", dropped)
+		}
+	}()
+}
+
+// Push queues the payload to be posted. If the queue is full, the oldest payload queued is dropped, or every payload
+// queued is merged into this one if a MergeFunc is set.
+func (w *Webhook) Push(payload []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	for {
+		select {
+		case w.queue <- payload:
+			return
+		default:
+		}
+
+		if w.merge != nil {
+			w.logger.Warnf("webhook queue is full, merging the payloads queued")
+			payload = w.mergeQueued(payload)
+			continue
+		}
+
+		select {
+		case <-w.queue:
+			w.logger.Warnf("webhook queue is full, dropping the oldest payload")
+		default:
+		}
+	}
+}
+
+// mergeQueued takes every payload queued and returns them merged, in order, into the one given.
+// Payloads failing to merge are dropped.
+func (w *Webhook) mergeQueued(payload []byte) []byte {
+	var queued [][]byte
+	for len(w.queue) > 0 {
+		select {
+		case older := <-w.queue:
+			queued = append(queued, older)
+		default:
+		}
+	}
+
+	queued = append(queued, payload)
+	merged := queued[0]
+	for _, newer := range queued[1:] {
+		result, err := w.merge(merged, newer)
+		if err != nil {
+			w.logger.Warnf("merging webhook payloads, dropping the older one: %v", err)
+			result = newer
+		}
+		merged = result
+	}
+
+	return merged
+}
+
+// Close stops accepting payloads and waits until the ones queued are posted, or the context passed to Start is done.
+func (w *Webhook) Close() {
+	w.stop()
+	<-w.done
+}
+
+// CloseWithin closes the webhook as Close does, abandoning the deliveries not done within the timeout.
+func (w *Webhook) CloseWithin(timeout time.Duration) {
+	w.stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.done:
+	case <-timer.C:
+		w.cancel()
+		<-w.done
+	}
+}
+
+// stop stops accepting payloads.
+func (w *Webhook) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+}
+
+// post sends the payload, retrying network errors, 408, 429 and 5xx responses. Other responses are not retried,
+// while the Retry-After header is honored on 429 and 503 ones.
+func (w *Webhook) post(ctx context.Context, payload []byte) error {
+	return w.policy.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("creating request: %w", err))
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if w.bearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+w.bearerToken)
+		}
+		if len(w.hmacSecret) > 0 {
+			req.Header.Set(signatureHeader, signaturePrefix+Sign(w.hmacSecret, payload))
+		}
+		if w.clusterName != "" {
+			req.Header.Set(clusterNameHeader, w.clusterName)
+		}
+		if w.nodeName != "" {
+			req.Header.Set(nodeNameHeader, w.nodeName)
+		}
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+		// drain the body before closing it to allow reusing the connection.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close() // nolint: errcheck
+
+		err = fmt.Errorf("webhook responded with status code %d", resp.StatusCode)
+		switch {
+		case resp.StatusCode < http.StatusMultipleChoices:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusServiceUnavailable:
+			if delay, ok := retryAfter(resp.Header); ok {
+				return retry.After(err, delay)
+			}
+			return err
+		case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode >= http.StatusInternalServerError:
+			return err
+		}
+
+		return retry.Permanent(err)
+	})
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload, as sent in the X-Discovery-Signature header
+// prefixed by sha256=.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload) // nolint: errcheck // hashes never fail to write
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,236 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	internalhttp "github.com/newrelic/nri-discovery-kubernetes/internal/http"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records the requests posted to it, answering with the status codes given in order and 200 afterwards.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	wr.bodies = append(wr.bodies, string(body))
+	wr.headers = append(wr.headers, r.Header.Clone())
+
+	if len(wr.statuses) > 0 {
+		status := wr.statuses[0]
+		wr.statuses = wr.statuses[1:]
+		// Retries are not delayed, so tests run fast.
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+	}
+}
+
+func (wr *webhookReceiver) received() ([]string, []http.Header) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.bodies, wr.headers
+}
+
+func webhookConfig(url string) *config.Config {
+	return &config.Config{
+		WebhookURL:       url,
+		WebhookQueueSize: 2,
+		ClusterName:      "test-cluster",
+		NodeName:         nodeName,
+		Timeout:          1000,
+		Retries:          retries,
+		Backoff:          retry.BackoffLinear,
+	}
+}
+
+func TestNewWebhook_NotConfigured(t *testing.T) {
+	assert.Nil(t, internalhttp.NewWebhook(&config.Config{}, log.New()))
+}
+
+func TestWebhook(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	c := webhookConfig(server.URL)
+	c.WebhookBearerToken = "token"
+	c.WebhookHMACSecret = "secret"
+
+	webhook := internalhttp.NewWebhook(c, log.New())
+	webhook.Start(context.Background())
+	webhook.Push([]byte(`[]`))
+	webhook.Close()
+
+	bodies, headers := receiver.received()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, `[]`, bodies[0])
+	assert.Equal(t, "application/json", headers[0].Get("Content-Type"))
+	assert.Equal(t, "Bearer token", headers[0].Get("Authorization"))
+	assert.Equal(t, "sha256="+internalhttp.Sign([]byte("secret"), []byte(`[]`)), headers[0].Get("X-Discovery-Signature"))
+	assert.Equal(t, "test-cluster", headers[0].Get("X-Discovery-Cluster-Name"))
+	assert.Equal(t, nodeName, headers[0].Get("X-Discovery-Node-Name"))
+
+	webhook.Push([]byte(`[]`))
+	assert.Len(t, bodies, 1, "Payloads pushed once closed are ignored")
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int
+	}{
+		{name: "service_unavailable", statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}, wantRequests: 3},
+		{name: "too_many_requests", statuses: []int{http.StatusTooManyRequests}, wantRequests: 2},
+		{name: "internal_error", statuses: []int{http.StatusInternalServerError}, wantRequests: 2},
+		{name: "exhausted", statuses: []int{503, 503, 503, 503, 503}, wantRequests: retries},
+		{name: "unauthorized_not_retried", statuses: []int{http.StatusUnauthorized}, wantRequests: 1},
+		{name: "bad_request_not_retried", statuses: []int{http.StatusBadRequest}, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &webhookReceiver{statuses: tt.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			webhook := internalhttp.NewWebhook(webhookConfig(server.URL), log.New())
+			webhook.Start(context.Background())
+			webhook.Push([]byte(`{}`))
+			webhook.Close()
+
+			bodies, _ := receiver.received()
+			assert.Len(t, bodies, tt.wantRequests)
+		})
+	}
+}
+
+func TestWebhookQueueDropsOldest(t *testing.T) {
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		receiver.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	webhook := internalhttp.NewWebhook(webhookConfig(server.URL), log.New())
+	webhook.Start(context.Background())
+
+	// The first payload is being posted while the rest wait in the queue, which holds 2.
+	webhook.Push([]byte(`1`))
+	<-arrived
+	webhook.Push([]byte(`2`))
+	webhook.Push([]byte(`3`))
+	webhook.Push([]byte(`4`))
+	close(release)
+	webhook.Close()
+
+	bodies, _ := receiver.received()
+	assert.Equal(t, []string{"1", "3", "4"}, bodies, "The oldest payload queued is dropped")
+}
+
+func TestWebhookQueueMerges(t *testing.T) {
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		receiver.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	webhook := internalhttp.NewWebhook(webhookConfig(server.URL), log.New())
+	webhook.SetMerge(func(older, newer []byte) ([]byte, error) {
+		return append(append(older, '+'), newer...), nil
+	})
+	webhook.Start(context.Background())
+
+	// The first payload is being posted while the rest wait in the queue, which holds 2.
+	webhook.Push([]byte(`1`))
+	<-arrived
+	webhook.Push([]byte(`2`))
+	webhook.Push([]byte(`3`))
+	webhook.Push([]byte(`4`))
+	close(release)
+	webhook.Close()
+
+	bodies, _ := receiver.received()
+	assert.Equal(t, []string{"1", "2+3+4"}, bodies, "Payloads queued are merged into the newer one")
+}
+
+func TestWebhookCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := webhookConfig(server.URL)
+	c.Retries = 100
+
+	webhook := internalhttp.NewWebhook(c, log.New())
+	webhook.Start(ctx)
+	webhook.Push([]byte(`{}`))
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		webhook.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the webhook waited for the retries of a cancelled context")
+	}
+}
+
+func TestWebhookCloseWithin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := webhookConfig(server.URL)
+	c.Retries = 100
+
+	webhook := internalhttp.NewWebhook(c, log.New())
+	webhook.Start(context.Background())
+	webhook.Push([]byte(`1`))
+	webhook.Push([]byte(`2`))
+
+	done := make(chan struct{})
+	go func() {
+		webhook.CloseWithin(10 * time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the webhook waited for the retries past the timeout")
+	}
+}
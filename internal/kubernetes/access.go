@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is an action on a resource which discovery may need to perform.
+type Permission struct {
+	Verb        string
+	Resource    string
+	Subresource string
+	// Namespace is empty for cluster scoped resources or to check every namespace.
+	Namespace string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %q", p.Verb, resource, p.Namespace)
+	}
+	return fmt.Sprintf("%s %s", p.Verb, resource)
+}
+
+// PermissionCheck is the outcome of reviewing whether the current identity is granted a Permission.
+type PermissionCheck struct {
+	Permission
+	Allowed bool
+	// Reason given by the authorizer, if any.
+	Reason string
+	// Err is set if the review could not be made.
+	Err error
+}
+
+// CheckPermissions reviews whether the identity of the client is granted each permission through a
+// SelfSubjectAccessReview, which every authenticated identity can create.
+func CheckPermissions(ctx context.Context, client kubernetes.Interface, permissions []Permission) []PermissionCheck {
+	checks := make([]PermissionCheck, 0, len(permissions))
+
+	for _, p := range permissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Verb:        p.Verb,
+					Resource:    p.Resource,
+					Subresource: p.Subresource,
+					Namespace:   p.Namespace,
+				},
+			},
+		}
+
+		check := PermissionCheck{Permission: p}
+		result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			check.Err = fmt.Errorf("reviewing access to %s: %w", p, err)
+		} else {
+			check.Allowed = result.Status.Allowed
+			check.Reason = result.Status.Reason
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
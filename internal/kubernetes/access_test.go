@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+
+		switch {
+		case attributes.Resource == "services" && attributes.Namespace == "broken":
+			return true, nil, errors.New("review failed")
+		case attributes.Subresource == "proxy":
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: false, Reason: "no RBAC policy matched"}
+		default:
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true}
+		}
+		return true, review, nil
+	})
+
+	checks := CheckPermissions(context.Background(), client, []Permission{
+		{Verb: "get", Resource: "nodes", Subresource: "proxy"},
+		{Verb: "list", Resource: "services", Namespace: "default"},
+		{Verb: "list", Resource: "services", Namespace: "broken"},
+	})
+	require.Len(t, checks, 3)
+
+	assert.Equal(t, "get nodes/proxy", checks[0].String())
+	assert.False(t, checks[0].Allowed)
+	assert.Equal(t, "no RBAC policy matched", checks[0].Reason)
+
+	assert.Equal(t, `list services in namespace "default"`, checks[1].String())
+	assert.True(t, checks[1].Allowed)
+	assert.NoError(t, checks[1].Err)
+
+	assert.False(t, checks[2].Allowed)
+	assert.Error(t, checks[2].Err)
+}
@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// apiServerKubelet implements Kubelet listing pods from the API server, for clusters where the kubelet is not reachable
+// either directly or through the API server proxy.
+type apiServerKubelet struct {
+	client      kubernetes.Interface
+	policy      retry.Policy
+	NodeName    string
+	ClusterName string
+}
+
+func (kube *apiServerKubelet) FindContainers(ctx context.Context, namespaces []string) ([]ContainerInfo, error) {
+	pods, err := kube.getPods(ctx, namespaces)
+	if err != nil && !IsPartialResult(err) {
+		return nil, err
+	}
+
+	var containers []ContainerInfo
+	for _, pod := range pods {
+		// the node name is taken from each pod since pods from every node are listed when NodeName is not set.
+		containers = append(containers, getContainers(kube.ClusterName, pod.Spec.NodeName, []corev1.Pod{pod})...)
+	}
+	return containers, err
+}
+
+func (kube *apiServerKubelet) getPods(ctx context.Context, namespaces []string) ([]corev1.Pod, error) {
+	selectors := []fields.Selector{fields.OneTermEqualSelector("status.phase", string(corev1.PodRunning))}
+	if kube.NodeName != "" {
+		selectors = append(selectors, fields.OneTermEqualSelector("spec.nodeName", kube.NodeName))
+	}
+	opts := metav1.ListOptions{FieldSelector: fields.AndSelectors(selectors...).String()}
+
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	var pods []corev1.Pod
+	var errs []error
+	for _, ns := range namespaces {
+		var podList *corev1.PodList
+		err := retryAPI(ctx, kube.policy, func() error {
+			var err error
+			podList, err = kube.client.CoreV1().Pods(ns).List(ctx, opts)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list pods from the API server in namespace %q: %w", ns, err))
+			continue
+		}
+
+		for _, pod := range podList.Items {
+			// Selectors are already applied by the API server, this check only guards against servers ignoring them.
+			if kube.NodeName != "" && pod.Spec.NodeName != kube.NodeName {
+				continue
+			}
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, partialResult(len(namespaces), errs)
+}
+
+// NewAPIServerKubelet constructs a Kubelet that lists the pods from the API server instead of querying the kubelet.
+// Pods are restricted to the configured node, or listed cluster-wide if no node name is set.
+func NewAPIServerKubelet(client kubernetes.Interface, config *config.Config) Kubelet {
+	return &apiServerKubelet{
+		client:      client,
+		policy:      retryPolicy(config),
+		NodeName:    config.NodeName,
+		ClusterName: config.ClusterName,
+	}
+}
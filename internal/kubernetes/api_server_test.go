@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func createScheduledPod(namespace, name, node string) *corev1.Pod {
+	pod := getPod(corev1.PodRunning, buildContainerStatusRunning(name))
+	pod.ObjectMeta = metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      map[string]string{"app": name},
+		Annotations: map[string]string{"team": "caos"},
+	}
+	pod.Spec = corev1.PodSpec{
+		NodeName: node,
+		Containers: []corev1.Container{
+			{
+				Name:  name,
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			},
+		},
+	}
+	pod.Status.PodIP = "10.1.0.1"
+	pod.Status.HostIP = "10.0.0.1"
+	return &pod
+}
+
+func TestAPIServerKubelet_FindContainers(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		createScheduledPod("default", "local", "testNode"),
+		createScheduledPod("other", "local-other-ns", "testNode"),
+		createScheduledPod("default", "remote", "remoteNode"),
+	)
+
+	t.Run("local_node", func(t *testing.T) {
+		kube := NewAPIServerKubelet(client, &config.Config{NodeName: "testNode", ClusterName: "testCluster"})
+
+		containers, err := kube.FindContainers(context.Background(), []string{"default"})
+		require.NoError(t, err)
+		require.Len(t, containers, 1)
+
+		expected := buildExpectedContainerInfo("local")
+		expected.Ports = PortsMap{"0": 8080, "http": 8080}
+		expected.PortProtocols = ProtocolsMap{"0": "TCP", "http": "TCP"}
+		expected.PodIP = "10.1.0.1"
+		expected.NodeIP = "10.0.0.1"
+		expected.PodName = "local"
+		expected.Namespace = "default"
+		expected.PodLabels = LabelsMap{"app": "local"}
+		expected.PodAnnotations = AnnotationsMap{"team": "caos"}
+		assert.Equal(t, expected, containers[0], "ContainerInfo matches the one built from the kubelet")
+	})
+
+	t.Run("local_node_all_namespaces", func(t *testing.T) {
+		kube := NewAPIServerKubelet(client, &config.Config{NodeName: "testNode"})
+
+		containers, err := kube.FindContainers(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Len(t, containers, 2)
+	})
+
+	t.Run("cluster_wide_without_node_name", func(t *testing.T) {
+		kube := NewAPIServerKubelet(client, &config.Config{})
+
+		containers, err := kube.FindContainers(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, containers, 3)
+
+		nodes := map[string]string{}
+		for _, c := range containers {
+			nodes[c.PodName] = c.NodeName
+		}
+		assert.Equal(t, "remoteNode", nodes["remote"])
+		assert.Equal(t, "testNode", nodes["local"])
+	})
+}
+
+func TestAPIServerKubelet_FindContainersPartialResult(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		createScheduledPod("default", "local", "testNode"),
+		createScheduledPod("forbidden", "hidden", "testNode"),
+	)
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "forbidden" {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("rbac"))
+		}
+		return false, nil, nil
+	})
+
+	kube := NewAPIServerKubelet(client, &config.Config{NodeName: "testNode"})
+
+	containers, err := kube.FindContainers(context.Background(), []string{"default", "forbidden"})
+	assert.True(t, IsPartialResult(err), "Forbidden namespace is reported as a partial result")
+	require.Len(t, containers, 1)
+	assert.Equal(t, "local", containers[0].PodName)
+
+	containers, err = kube.FindContainers(context.Background(), []string{"forbidden"})
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.False(t, IsPartialResult(err), "Nothing could be listed")
+	assert.Empty(t, containers)
+}
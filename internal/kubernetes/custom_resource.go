@@ -0,0 +1,210 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// CustomResourceInfo represents discovery-specific format for found custom resources via Kubernetes API.
+type CustomResourceInfo struct {
+	APIVersion  string
+	Kind        string
+	Resource    string
+	Name        string
+	Namespace   string
+	Labels      LabelsMap
+	Annotations AnnotationsMap
+	Variables   map[string]interface{}
+	Cluster     string
+}
+
+// CustomResourceDiscoverer defines what functionality custom resource discovery client provides.
+type CustomResourceDiscoverer interface {
+	FindCustomResources(ctx context.Context, namespaces []string) ([]CustomResourceInfo, error)
+}
+
+type customResource struct {
+	gvr           schema.GroupVersionResource
+	clusterScoped bool
+	labelSelector string
+	variables     map[string]*jsonpath.JSONPath
+}
+
+type customResourceDiscoverer struct {
+	client      dynamic.Interface
+	policy      retry.Policy
+	resources   []customResource
+	ClusterName string
+}
+
+func (crd *customResourceDiscoverer) FindCustomResources(ctx context.Context, namespaces []string) ([]CustomResourceInfo, error) {
+	var result []CustomResourceInfo
+	var errs []error
+	failedResources := 0
+
+	for _, cr := range crd.resources {
+		objects, err := crd.getObjects(ctx, cr, namespaces)
+		var partialErr *PartialResultError
+		switch {
+		case errors.As(err, &partialErr):
+			errs = append(errs, partialErr.Errs...)
+		case err != nil:
+			errs = append(errs, err)
+			failedResources++
+			continue
+		}
+
+		for _, obj := range objects {
+			result = append(result, transformCustomResource(crd.ClusterName, cr, obj))
+		}
+	}
+
+	if len(errs) == 0 {
+		return result, nil
+	}
+	if failedResources == len(crd.resources) {
+		return nil, errors.Join(errs...)
+	}
+	return result, &PartialResultError{Errs: errs}
+}
+
+func (crd *customResourceDiscoverer) getObjects(ctx context.Context, cr customResource, namespaces []string) ([]unstructured.Unstructured, error) {
+	opts := metav1.ListOptions{LabelSelector: cr.labelSelector}
+
+	// Cluster scoped resources and empty namespaces are listed at once.
+	if cr.clusterScoped || len(namespaces) == 0 {
+		list, err := crd.list(ctx, crd.client.Resource(cr.gvr), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", cr.gvr.String(), err)
+		}
+		return list.Items, nil
+	}
+
+	var objects []unstructured.Unstructured
+	var errs []error
+	for _, ns := range namespaces {
+		list, err := crd.list(ctx, crd.client.Resource(cr.gvr).Namespace(ns), opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %s in namespace %s: %w", cr.gvr.String(), ns, err))
+			continue
+		}
+		objects = append(objects, list.Items...)
+	}
+
+	return objects, partialResult(len(namespaces), errs)
+}
+
+func (crd *customResourceDiscoverer) list(ctx context.Context, client dynamic.ResourceInterface, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	var list *unstructured.UnstructuredList
+	err := retryAPI(ctx, crd.policy, func() error {
+		var err error
+		list, err = client.List(ctx, opts)
+		return err
+	})
+	return list, err
+}
+
+func transformCustomResource(clusterName string, cr customResource, obj unstructured.Unstructured) CustomResourceInfo {
+	variables := make(map[string]interface{})
+	for name, path := range cr.variables {
+		if value, found := evaluate(path, obj.UnstructuredContent()); found {
+			variables[name] = value
+		}
+	}
+
+	return CustomResourceInfo{
+		APIVersion:  obj.GetAPIVersion(),
+		Kind:        obj.GetKind(),
+		Resource:    cr.gvr.Resource,
+		Name:        obj.GetName(),
+		Namespace:   obj.GetNamespace(),
+		Labels:      obj.GetLabels(),
+		Annotations: obj.GetAnnotations(),
+		Variables:   variables,
+		Cluster:     clusterName,
+	}
+}
+
+// evaluate returns the value found by the given path. Multiple results are returned as a slice.
+func evaluate(path *jsonpath.JSONPath, content map[string]interface{}) (interface{}, bool) {
+	results, err := path.FindResults(content)
+	if err != nil {
+		return nil, false
+	}
+
+	var values []interface{}
+	for _, result := range results {
+		for _, r := range result {
+			if r.IsValid() && r.CanInterface() {
+				values = append(values, r.Interface())
+			}
+		}
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, false
+	case 1:
+		return values[0], true
+	default:
+		return values, true
+	}
+}
+
+func parseCustomResource(cr config.CustomResource) (customResource, error) {
+	gv, err := schema.ParseGroupVersion(cr.APIVersion)
+	if err != nil {
+		return customResource{}, fmt.Errorf("parsing apiVersion %q: %w", cr.APIVersion, err)
+	}
+
+	parsed := customResource{
+		gvr:           gv.WithResource(cr.Resource),
+		clusterScoped: cr.ClusterScoped,
+		labelSelector: cr.LabelSelector,
+		variables:     make(map[string]*jsonpath.JSONPath, len(cr.Variables)),
+	}
+
+	for name, expression := range cr.Variables {
+		// Accept the relaxed syntax without braces, e.g. .status.host
+		if !strings.HasPrefix(expression, "{") {
+			expression = "{" + expression + "}"
+		}
+
+		path := jsonpath.New(name).AllowMissingKeys(true)
+		if err := path.Parse(expression); err != nil {
+			return customResource{}, fmt.Errorf("parsing JSONPath of variable %q for %s: %w", name, parsed.gvr.String(), err)
+		}
+		parsed.variables[name] = path
+	}
+
+	return parsed, nil
+}
+
+// NewCustomResourceDiscoverer creates a new custom resource discoverer for the resources declared in the config.
+func NewCustomResourceDiscoverer(client dynamic.Interface, config *config.Config) (CustomResourceDiscoverer, error) {
+	resources := make([]customResource, 0, len(config.CustomResources))
+	for _, cr := range config.CustomResources {
+		parsed, err := parseCustomResource(cr)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, parsed)
+	}
+
+	return &customResourceDiscoverer{
+		client:      client,
+		policy:      retryPolicy(config),
+		resources:   resources,
+		ClusterName: config.ClusterName,
+	}, nil
+}
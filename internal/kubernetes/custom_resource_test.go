@@ -0,0 +1,171 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var kafkaGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkas"}
+
+func kafkaObject(namespace, name, bootstrap string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "kafka.strimzi.io/v1beta2",
+			"kind":       "Kafka",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"app": "kafka",
+				},
+			},
+			"spec": map[string]interface{}{
+				"kafka": map[string]interface{}{
+					"replicas": int64(3),
+				},
+			},
+			"status": map[string]interface{}{
+				"listeners": []interface{}{
+					map[string]interface{}{"name": "plain", "bootstrapServers": bootstrap},
+					map[string]interface{}{"name": "tls", "bootstrapServers": bootstrap + "-tls"},
+				},
+			},
+		},
+	}
+}
+
+func fakeCustomResourceDiscoverer(t *testing.T, resources []config.CustomResource, objects ...runtime.Object) CustomResourceDiscoverer {
+	t.Helper()
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{kafkaGVR: "KafkaList"},
+		objects...,
+	)
+
+	crd, err := NewCustomResourceDiscoverer(client, &config.Config{
+		ClusterName:     testClusterName,
+		CustomResources: resources,
+	})
+	require.NoError(t, err)
+
+	return crd
+}
+
+func TestFindCustomResources(t *testing.T) {
+	resources := []config.CustomResource{
+		{
+			APIVersion: "kafka.strimzi.io/v1beta2",
+			Resource:   "kafkas",
+			Variables: map[string]string{
+				"replicas":  ".spec.kafka.replicas",
+				"bootstrap": `{.status.listeners[?(@.name=="plain")].bootstrapServers}`,
+				"listeners": "{.status.listeners[*].name}",
+				"missing":   ".status.missing",
+			},
+		},
+	}
+
+	crd := fakeCustomResourceDiscoverer(t, resources,
+		kafkaObject("kafka", "my-cluster", "my-cluster-kafka-bootstrap:9092"),
+		kafkaObject("other", "other-cluster", "other-cluster-kafka-bootstrap:9092"),
+	)
+
+	t.Run("all_namespaces", func(t *testing.T) {
+		found, err := crd.FindCustomResources(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+	})
+
+	t.Run("single_namespace", func(t *testing.T) {
+		found, err := crd.FindCustomResources(context.Background(), []string{"kafka"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+
+		cr := found[0]
+		assert.Equal(t, "my-cluster", cr.Name)
+		assert.Equal(t, "kafka", cr.Namespace)
+		assert.Equal(t, "Kafka", cr.Kind)
+		assert.Equal(t, "kafkas", cr.Resource)
+		assert.Equal(t, "kafka.strimzi.io/v1beta2", cr.APIVersion)
+		assert.Equal(t, testClusterName, cr.Cluster)
+		assert.Equal(t, LabelsMap{"app": "kafka"}, cr.Labels)
+
+		assert.Equal(t, int64(3), cr.Variables["replicas"])
+		assert.Equal(t, "my-cluster-kafka-bootstrap:9092", cr.Variables["bootstrap"])
+		assert.Equal(t, []interface{}{"plain", "tls"}, cr.Variables["listeners"])
+		assert.NotContains(t, cr.Variables, "missing")
+	})
+}
+
+func TestNewCustomResourceDiscoverer_InvalidDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource config.CustomResource
+	}{
+		{
+			name:     "invalid apiVersion",
+			resource: config.CustomResource{APIVersion: "a/b/c", Resource: "kafkas"},
+		},
+		{
+			name: "invalid JSONPath",
+			resource: config.CustomResource{
+				APIVersion: "kafka.strimzi.io/v1beta2",
+				Resource:   "kafkas",
+				Variables:  map[string]string{"broken": "{.status[}"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCustomResourceDiscoverer(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), &config.Config{
+				CustomResources: []config.CustomResource{tt.resource},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFindCustomResources_PartialResult(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{kafkaGVR: "KafkaList"},
+		kafkaObject("kafka", "my-cluster", "my-cluster-kafka-bootstrap:9092"),
+	)
+	client.PrependReactor("list", "kafkas", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "forbidden" {
+			return true, nil, apierrors.NewForbidden(kafkaGVR.GroupResource(), "", errors.New("rbac"))
+		}
+		return false, nil, nil
+	})
+
+	crd, err := NewCustomResourceDiscoverer(client, &config.Config{
+		ClusterName:     testClusterName,
+		CustomResources: []config.CustomResource{{APIVersion: "kafka.strimzi.io/v1beta2", Resource: "kafkas"}},
+	})
+	require.NoError(t, err)
+
+	found, err := crd.FindCustomResources(context.Background(), []string{"kafka", "forbidden"})
+	var partialErr *PartialResultError
+	require.ErrorAs(t, err, &partialErr)
+	assert.Len(t, partialErr.Errs, 1)
+	require.Len(t, found, 1)
+	assert.Equal(t, "my-cluster", found[0].Name)
+
+	found, err = crd.FindCustomResources(context.Background(), []string{"forbidden"})
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.False(t, IsPartialResult(err), "Nothing could be listed")
+	assert.Empty(t, found)
+}
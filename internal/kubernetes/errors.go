@@ -0,0 +1,36 @@
+package kubernetes
+
+import "errors"
+
+// PartialResultError is returned along with the objects that could be found when some of the requests
+// made to find them failed, e.g. listing services in a namespace forbidden by RBAC.
+type PartialResultError struct {
+	Errs []error
+}
+
+func (e *PartialResultError) Error() string {
+	return errors.Join(e.Errs...).Error()
+}
+
+func (e *PartialResultError) Unwrap() []error {
+	return e.Errs
+}
+
+// IsPartialResult checks if the error was returned along with the objects that could be found.
+func IsPartialResult(err error) bool {
+	var partialErr *PartialResultError
+	return errors.As(err, &partialErr)
+}
+
+// partialResult returns the error to report after making the given number of requests: nil if none of them failed,
+// a PartialResultError if only some of them did, or the errors joined if every request failed.
+func partialResult(requests int, errs []error) error {
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) < requests:
+		return &PartialResultError{Errs: errs}
+	default:
+		return errors.Join(errs...)
+	}
+}
@@ -1,7 +1,9 @@
 package kubernetes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -10,15 +12,20 @@ import (
 	"github.com/newrelic/nri-discovery-kubernetes/internal/http"
 	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
 	podsPath = "/pods"
 )
 
+var errUnexpectedToken = errors.New("unexpected token in pod list")
+
 type (
 	// PortsMap stores container ports indexed by name.
 	PortsMap map[string]int32
+	// ProtocolsMap stores the protocol of container ports indexed by name.
+	ProtocolsMap map[string]string
 	// LabelsMap stores Pod labels.
 	LabelsMap map[string]string
 	// AnnotationsMap stores Pod annotations.
@@ -32,10 +39,12 @@ type ContainerInfo struct {
 	Image          string
 	ImageID        string
 	Ports          PortsMap
+	PortProtocols  ProtocolsMap
 	PodLabels      LabelsMap
 	PodAnnotations AnnotationsMap
 	PodIP          string
 	PodName        string
+	PodUID         string
 	NodeName       string
 	NodeIP         string
 	Namespace      string
@@ -44,7 +53,7 @@ type ContainerInfo struct {
 
 // Kubelet defines what functionality kubelet client provides.
 type Kubelet interface {
-	FindContainers(namespaces []string) ([]ContainerInfo, error)
+	FindContainers(ctx context.Context, namespaces []string) ([]ContainerInfo, error)
 }
 
 type kubelet struct {
@@ -53,17 +62,16 @@ type kubelet struct {
 	ClusterName string
 }
 
-func (kube *kubelet) FindContainers(namespaces []string) ([]ContainerInfo, error) {
-	allPods, err := kube.getPods()
+func (kube *kubelet) FindContainers(ctx context.Context, namespaces []string) ([]ContainerInfo, error) {
+	pods, err := kube.getPods(ctx, namespaces)
 	if err != nil {
 		return nil, err
 	}
-	pods := filterByNamespace(allPods, namespaces)
 	return getContainers(kube.ClusterName, kube.NodeName, pods), nil
 }
 
-func (kube *kubelet) getPods() ([]corev1.Pod, error) {
-	resp, err := kube.client.Get(podsPath)
+func (kube *kubelet) getPods(ctx context.Context, namespaces []string) ([]corev1.Pod, error) {
+	resp, err := kube.client.Get(ctx, podsPath)
 	if err != nil {
 		err = fmt.Errorf("failed to execute request against kubelet: %w ", err)
 		return []corev1.Pod{}, err
@@ -71,32 +79,164 @@ func (kube *kubelet) getPods() ([]corev1.Pod, error) {
 
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	pods, err := decodePods(resp.Body, namespaces)
 	if err != nil {
-		err = fmt.Errorf("failed read the response from kubelet: %w ", err)
-		return []corev1.Pod{}, err
+		err = fmt.Errorf("failed to unmarshall result in to list of pods: %w", err)
 	}
+	return pods, err
+}
 
-	pods := &corev1.PodList{}
-	err = json.Unmarshal(respBody, pods)
-	if err != nil {
-		err = fmt.Errorf("failed to unmarshall result in to list of pods: %w", err)
+// kubeletPod holds the subset of a Pod needed to build ContainerInfo, so the rest of the
+// kubelet response, e.g. managed fields or volumes, is skipped while decoding.
+type kubeletPod struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		UID         string            `json:"uid"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Name  string `json:"name"`
+			Ports []struct {
+				Name          string          `json:"name"`
+				ContainerPort int32           `json:"containerPort"`
+				Protocol      corev1.Protocol `json:"protocol"`
+			} `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase             corev1.PodPhase `json:"phase"`
+		PodIP             string          `json:"podIP"`
+		HostIP            string          `json:"hostIP"`
+		ContainerStatuses []struct {
+			Name        string `json:"name"`
+			Image       string `json:"image"`
+			ImageID     string `json:"imageID"`
+			ContainerID string `json:"containerID"`
+			State       struct {
+				Running *struct{} `json:"running"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+func (kp *kubeletPod) toPod() corev1.Pod {
+	pod := corev1.Pod{}
+	pod.Name = kp.Metadata.Name
+	pod.Namespace = kp.Metadata.Namespace
+	pod.UID = types.UID(kp.Metadata.UID)
+	pod.Labels = kp.Metadata.Labels
+	pod.Annotations = kp.Metadata.Annotations
+	pod.Status.Phase = kp.Status.Phase
+	pod.Status.PodIP = kp.Status.PodIP
+	pod.Status.HostIP = kp.Status.HostIP
+
+	for _, c := range kp.Spec.Containers {
+		container := corev1.Container{Name: c.Name}
+		for _, p := range c.Ports {
+			container.Ports = append(container.Ports, corev1.ContainerPort{Name: p.Name, ContainerPort: p.ContainerPort, Protocol: p.Protocol})
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
 	}
-	return pods.Items, err
+
+	for _, cs := range kp.Status.ContainerStatuses {
+		status := corev1.ContainerStatus{
+			Name:        cs.Name,
+			Image:       cs.Image,
+			ImageID:     cs.ImageID,
+			ContainerID: cs.ContainerID,
+		}
+		if cs.State.Running != nil {
+			status.State.Running = &corev1.ContainerStateRunning{}
+		}
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, status)
+	}
+
+	return pod
 }
 
-func filterByNamespace(allPods []corev1.Pod, namespaces []string) []corev1.Pod {
-	if len(namespaces) == 0 {
-		return allPods
+// decodePods reads a PodList as a stream, one item at a time, keeping only running pods
+// in the given namespaces, or in all of them if none are given.
+func decodePods(r io.Reader, namespaces []string) ([]corev1.Pod, error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
 	}
 
-	var result []corev1.Pod
-	for _, pod := range allPods {
-		if utils.Contains(namespaces, pod.Namespace) {
-			result = append(result, pod)
+	var pods []corev1.Pod
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if key != "items" {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		items, err := decodeItems(dec, namespaces)
+		if err != nil {
+			return nil, err
 		}
+		pods = append(pods, items...)
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
 	}
-	return result
+
+	return pods, nil
+}
+
+func decodeItems(dec *json.Decoder, namespaces []string) ([]corev1.Pod, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	// A list without pods might have null items.
+	if t == nil {
+		return nil, nil
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: items is not an array", errUnexpectedToken)
+	}
+
+	var pods []corev1.Pod
+	for dec.More() {
+		var kp kubeletPod
+		if err := dec.Decode(&kp); err != nil {
+			return nil, err
+		}
+
+		if kp.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if len(namespaces) > 0 && !utils.Contains(namespaces, kp.Metadata.Namespace) {
+			continue
+		}
+
+		pods = append(pods, kp.toPod())
+	}
+
+	return pods, expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, expected json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("%w: expected %q, got %v", errUnexpectedToken, expected, t)
+	}
+	return nil
 }
 
 func getContainers(clusterName string, nodeName string, pods []corev1.Pod) []ContainerInfo {
@@ -119,10 +259,12 @@ func getContainers(clusterName string, nodeName string, pods []corev1.Pod) []Con
 				Image:          cs.Image,
 				ImageID:        cs.ImageID,
 				Ports:          ports,
+				PortProtocols:  getProtocols(pod, idx),
 				PodIP:          pod.Status.PodIP,
 				PodLabels:      pod.Labels,
 				PodAnnotations: pod.Annotations,
 				PodName:        pod.Name,
+				PodUID:         string(pod.UID),
 				NodeName:       nodeName,
 				NodeIP:         pod.Status.HostIP,
 				Namespace:      pod.Namespace,
@@ -150,6 +292,24 @@ func getPorts(pod corev1.Pod, containerIndex int) PortsMap {
 	return ports
 }
 
+// getProtocols returns the protocol of each port, indexed as getPorts does. Ports without protocol are TCP.
+func getProtocols(pod corev1.Pod, containerIndex int) ProtocolsMap {
+	protocols := make(ProtocolsMap)
+	if len(pod.Spec.Containers) > containerIndex {
+		for portIndex, port := range pod.Spec.Containers[containerIndex].Ports {
+			protocol := string(port.Protocol)
+			if protocol == "" {
+				protocol = string(corev1.ProtocolTCP)
+			}
+			protocols[strconv.Itoa(portIndex)] = protocol
+			if len(port.Name) > 0 {
+				protocols[port.Name] = protocol
+			}
+		}
+	}
+	return protocols
+}
+
 // New validates and constructs Kubelet client.
 func New(client *http.Client, config *config.Config) Kubelet {
 	return &kubelet{
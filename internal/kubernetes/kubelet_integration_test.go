@@ -35,7 +35,7 @@ func Test_Kubelet_client_finds_containers_info_using_TLS_and_client_certificate_
 
 	kubelet, _ := singleNodeClusterKubelet(t)
 
-	_, err := kubelet.FindContainers(nil)
+	_, err := kubelet.FindContainers(contextWithDeadline(t), nil)
 	require.NoErrorf(t, err, "finding containers")
 }
 
@@ -77,7 +77,7 @@ func Test_Kubelet_client_expects_pod_container_statuses_to_have_populated(t *tes
 
 	pod = withRunningTestPod(t, clientset.CoreV1().Pods(ns), pod)
 
-	containersInfo, err := kubelet.FindContainers([]string{ns})
+	containersInfo, err := kubelet.FindContainers(contextWithDeadline(t), []string{ns})
 	require.NoErrorf(t, err, "finding containers")
 
 	require.Equalf(t, len(containersInfo), 1, "expected only one container")
@@ -190,7 +190,7 @@ func singleNodeClusterKubelet(t *testing.T) (kubernetes.Kubelet, *k8s.Clientset)
 
 	connector := http.DefaultConnector(clientset, conf, cfg, logger)
 
-	httpClient, err := http.NewClient(connector, http.WithMaxRetries(5))
+	httpClient, err := http.NewClient(contextWithDeadline(t), connector, http.WithMaxRetries(5))
 	require.NoErrorf(t, err, "creating HTTP Client")
 
 	kubelet := kubernetes.New(httpClient, conf)
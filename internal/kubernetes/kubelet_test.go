@@ -1,10 +1,18 @@
 package kubernetes
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
+	"github.com/newrelic/nri-discovery-kubernetes/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func getPod(phase corev1.PodPhase, containerStatus ...corev1.ContainerStatus) corev1.Pod {
@@ -60,6 +68,7 @@ func buildExpectedContainerInfo(containerName string) ContainerInfo {
 		Image:          "k8s.gcr.io/kube-scheduler:v1.18.2",
 		ImageID:        "docker-pullable://k8s.gcr.io/kube-scheduler@sha256:69f90a33b64c99e4c78e3cae36b0c767729b5a54203aa35524b1033708d1b482",
 		Ports:          PortsMap{},
+		PortProtocols:  ProtocolsMap{},
 		PodIP:          "",
 		PodLabels:      nil,
 		PodAnnotations: nil,
@@ -161,3 +170,166 @@ func TestGetContainers(t *testing.T) {
 		})
 	}
 }
+
+// syntheticPodList builds a kubelet /pods payload similar to the ones found on busy nodes,
+// with big annotations and managed fields which discovery does not need.
+func syntheticPodList(tb testing.TB, count int) []byte {
+	tb.Helper()
+
+	list := corev1.PodList{
+		TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+	}
+
+	bigAnnotation := strings.Repeat("x", 4096)
+	for i := 0; i < count; i++ {
+		phase := corev1.PodRunning
+		if i%10 == 0 {
+			phase = corev1.PodSucceeded
+		}
+
+		pod := getPod(phase, buildContainerStatusRunning("app"), buildContainerStatusRunning("sidecar"))
+		pod.ObjectMeta = metav1.ObjectMeta{
+			Name:      fmt.Sprintf("pod-%d", i),
+			Namespace: fmt.Sprintf("namespace-%d", i%5),
+			Labels:    map[string]string{"app": fmt.Sprintf("app-%d", i)},
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": bigAnnotation,
+				"team": "caos",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubelet", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+			},
+		}
+		pod.Spec = corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "app",
+					Image: "nginx:latest",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}, {ContainerPort: 9090}},
+					Env:   []corev1.EnvVar{{Name: "CONFIG", Value: bigAnnotation}},
+				},
+				{
+					Name:  "sidecar",
+					Image: "envoy:latest",
+				},
+			},
+			Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		}
+		pod.Status.PodIP = fmt.Sprintf("10.1.%d.%d", i/256, i%256)
+		pod.Status.HostIP = "10.0.0.1"
+
+		list.Items = append(list.Items, pod)
+	}
+
+	payload, err := json.Marshal(list)
+	require.NoError(tb, err)
+
+	return payload
+}
+
+// unmarshalPodList is how the kubelet response was processed before being decoded as a stream.
+func unmarshalPodList(payload []byte, namespaces []string) ([]corev1.Pod, error) {
+	list := &corev1.PodList{}
+	if err := json.Unmarshal(payload, list); err != nil {
+		return nil, err
+	}
+
+	if len(namespaces) == 0 {
+		return list.Items, nil
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if utils.Contains(namespaces, pod.Namespace) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func TestDecodePods(t *testing.T) {
+	payload := syntheticPodList(t, 50)
+
+	for _, namespaces := range [][]string{nil, {"namespace-1", "namespace-3"}, {"missing"}} {
+		t.Run(fmt.Sprintf("namespaces_%v", namespaces), func(t *testing.T) {
+			expectedPods, err := unmarshalPodList(payload, namespaces)
+			require.NoError(t, err)
+
+			pods, err := decodePods(bytes.NewReader(payload), namespaces)
+			require.NoError(t, err)
+
+			for _, pod := range pods {
+				assert.Equal(t, corev1.PodRunning, pod.Status.Phase, "Only running pods are kept")
+			}
+			assert.Equal(t,
+				getContainers("testCluster", "testNode", expectedPods),
+				getContainers("testCluster", "testNode", pods),
+				"ContainerInfo matches the one built from the whole PodList",
+			)
+		})
+	}
+}
+
+func TestDecodePods_EdgeCases(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		payload   string
+		expectErr bool
+	}{
+		{testName: "null_items", payload: `{"kind":"PodList","items":null}`},
+		{testName: "no_items", payload: `{"kind":"PodList","metadata":{}}`},
+		{testName: "empty_items", payload: `{"items":[]}`},
+		{testName: "not_an_object", payload: `[]`, expectErr: true},
+		{testName: "items_not_an_array", payload: `{"items":{}}`, expectErr: true},
+		{testName: "truncated", payload: `{"items":[{"metadata":{"name":"pod"`, expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.testName, func(t *testing.T) {
+			pods, err := decodePods(strings.NewReader(testCase.payload), nil)
+			if testCase.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, pods)
+		})
+	}
+}
+
+// BenchmarkDecodePods compares decoding the pods as a stream with unmarshaling the whole response, as it used to be.
+// The stream allocates a fraction of the memory, while taking about the same time, since every byte of the response
+// is still scanned.
+func BenchmarkDecodePods(b *testing.B) {
+	payload := syntheticPodList(b, 500)
+	namespaces := []string{"namespace-0", "namespace-1"}
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+		for i := 0; i < b.N; i++ {
+			pods, err := decodePods(bytes.NewReader(payload), namespaces)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = getContainers("testCluster", "testNode", pods)
+		}
+	})
+
+	b.Run("unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+		for i := 0; i < b.N; i++ {
+			// Copied as io.ReadAll used to do.
+			body, err := io.ReadAll(bytes.NewReader(payload))
+			if err != nil {
+				b.Fatal(err)
+			}
+			pods, err := unmarshalPodList(body, namespaces)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = getContainers("testCluster", "testNode", pods)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type (
+	// ConditionsMap stores Node condition statuses indexed by condition type.
+	ConditionsMap map[string]string
+)
+
+// NodeInfo represents discovery-specific format for found Nodes via Kubernetes API.
+type NodeInfo struct {
+	Name                    string
+	InternalIP              string
+	ExternalIP              string
+	Hostname                string
+	KubeletPort             int32
+	OperatingSystem         string
+	Architecture            string
+	KernelVersion           string
+	OSImage                 string
+	ContainerRuntimeVersion string
+	KubeletVersion          string
+	Conditions              ConditionsMap
+	Labels                  LabelsMap
+	Annotations             AnnotationsMap
+	Cluster                 string
+}
+
+// NodeDiscoverer defines what functionality node discovery client provides.
+type NodeDiscoverer interface {
+	FindNodes(ctx context.Context) ([]NodeInfo, error)
+}
+
+type nodeDiscoverer struct {
+	client      kubernetes.Interface
+	policy      retry.Policy
+	NodeName    string
+	ClusterName string
+}
+
+// FindNodes returns the local node when the node name is known, and every node in the cluster otherwise.
+func (nd *nodeDiscoverer) FindNodes(ctx context.Context) ([]NodeInfo, error) {
+	nodes, err := nd.getNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return transformNodes(nd.ClusterName, nodes), nil
+}
+
+func (nd *nodeDiscoverer) getNodes(ctx context.Context) ([]corev1.Node, error) {
+	if nd.NodeName != "" {
+		var node *corev1.Node
+		err := retryAPI(ctx, nd.policy, func() error {
+			var err error
+			node, err = nd.client.CoreV1().Nodes().Get(ctx, nd.NodeName, metav1.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %w", nd.NodeName, err)
+		}
+		return []corev1.Node{*node}, nil
+	}
+
+	var nodeList *corev1.NodeList
+	err := retryAPI(ctx, nd.policy, func() error {
+		var err error
+		nodeList, err = nd.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return nodeList.Items, nil
+}
+
+func transformNodes(clusterName string, nodes []corev1.Node) []NodeInfo {
+	var result []NodeInfo
+
+	for _, node := range nodes {
+		conditions := make(ConditionsMap, len(node.Status.Conditions))
+		for _, c := range node.Status.Conditions {
+			conditions[string(c.Type)] = string(c.Status)
+		}
+
+		info := node.Status.NodeInfo
+		nodeInfo := NodeInfo{
+			Name:                    node.Name,
+			InternalIP:              nodeAddress(node, corev1.NodeInternalIP),
+			ExternalIP:              nodeAddress(node, corev1.NodeExternalIP),
+			Hostname:                nodeAddress(node, corev1.NodeHostName),
+			KubeletPort:             node.Status.DaemonEndpoints.KubeletEndpoint.Port,
+			OperatingSystem:         info.OperatingSystem,
+			Architecture:            info.Architecture,
+			KernelVersion:           info.KernelVersion,
+			OSImage:                 info.OSImage,
+			ContainerRuntimeVersion: info.ContainerRuntimeVersion,
+			KubeletVersion:          info.KubeletVersion,
+			Conditions:              conditions,
+			Labels:                  node.Labels,
+			Annotations:             node.Annotations,
+			Cluster:                 clusterName,
+		}
+		result = append(result, nodeInfo)
+	}
+
+	return result
+}
+
+// nodeAddress returns the first address of the given type, or empty if the node has none.
+func nodeAddress(node corev1.Node, addressType corev1.NodeAddressType) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == addressType {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+// NewNodeDiscoverer creates a new node discoverer using the provided client.
+func NewNodeDiscoverer(client kubernetes.Interface, config *config.Config) NodeDiscoverer {
+	return &nodeDiscoverer{
+		client:      client,
+		policy:      retryPolicy(config),
+		NodeName:    config.NodeName,
+		ClusterName: config.ClusterName,
+	}
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createNode(name, internalIP string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"kubernetes.io/os": "linux"},
+			Annotations: map[string]string{"node.alpha.kubernetes.io/ttl": "0"},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: internalIP},
+			},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: 10250},
+			},
+			NodeInfo: corev1.NodeSystemInfo{
+				OperatingSystem:         "linux",
+				Architecture:            "arm64",
+				KernelVersion:           "6.1.0",
+				OSImage:                 "Ubuntu 22.04",
+				ContainerRuntimeVersion: "containerd://1.7.0",
+				KubeletVersion:          "v1.31.1",
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+}
+
+func TestFindNodes(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		createNode("node-1", "10.0.0.1"),
+		createNode("node-2", "10.0.0.2"),
+	)
+
+	t.Run("local_node_when_node_name_is_set", func(t *testing.T) {
+		nd := NewNodeDiscoverer(client, &config.Config{NodeName: "node-2", ClusterName: testClusterName})
+
+		nodes, err := nd.FindNodes(context.Background())
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+
+		n := nodes[0]
+		assert.Equal(t, "node-2", n.Name)
+		assert.Equal(t, "10.0.0.2", n.InternalIP)
+		assert.Equal(t, "", n.ExternalIP)
+		assert.Equal(t, "node-2", n.Hostname)
+		assert.Equal(t, int32(10250), n.KubeletPort)
+		assert.Equal(t, "linux", n.OperatingSystem)
+		assert.Equal(t, "arm64", n.Architecture)
+		assert.Equal(t, "6.1.0", n.KernelVersion)
+		assert.Equal(t, "Ubuntu 22.04", n.OSImage)
+		assert.Equal(t, "containerd://1.7.0", n.ContainerRuntimeVersion)
+		assert.Equal(t, "v1.31.1", n.KubeletVersion)
+		assert.Equal(t, ConditionsMap{"Ready": "True", "MemoryPressure": "False"}, n.Conditions)
+		assert.Equal(t, LabelsMap{"kubernetes.io/os": "linux"}, n.Labels)
+		assert.Equal(t, testClusterName, n.Cluster)
+	})
+
+	t.Run("all_nodes_without_node_name", func(t *testing.T) {
+		nd := NewNodeDiscoverer(client, &config.Config{ClusterName: testClusterName})
+
+		nodes, err := nd.FindNodes(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, nodes, 2)
+	})
+
+	t.Run("missing_local_node", func(t *testing.T) {
+		nd := NewNodeDiscoverer(client, &config.Config{NodeName: "missing"})
+
+		_, err := nd.FindNodes(context.Background())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrForbidden is returned by Preflight when the items configured cannot be discovered at all.
+var ErrForbidden = errors.New("forbidden to the service account")
+
+// Preflight checks the permissions needed by the features configured before discovering, returning a copy of the
+// config with the features not permitted skipped, along with a description of each of them. The items configured
+// are still discovered, unless they cannot be at all, which is returned as an ErrForbidden error.
+// Permissions which cannot be reviewed are assumed to be granted, so discovery behaves as without the preflight.
+func Preflight(ctx context.Context, client kubernetes.Interface, c *config.Config, logger *logrus.Logger) (*config.Config, []string, error) {
+	adjusted := *c
+	var degraded []string
+
+	allowed := func(p Permission) bool {
+		check := CheckPermissions(ctx, client, []Permission{p})[0]
+		if check.Err != nil {
+			logger.Debugf("assuming permission is granted: %v", check.Err)
+			return true
+		}
+		return check.Allowed
+	}
+
+	// The local node is got by its name, while every node is listed otherwise.
+	nodesPermission := Permission{Verb: "get", Resource: "nodes"}
+	if c.NodeName == "" {
+		nodesPermission.Verb = "list"
+	}
+
+	if c.Port == 0 || c.DiscoverNodes {
+		if !allowed(nodesPermission) {
+			if c.DiscoverNodes {
+				return nil, nil, fmt.Errorf("%s is %w", nodesPermission, ErrForbidden)
+			}
+			adjusted.Port = config.DefaultPort
+			degraded = append(degraded, fmt.Sprintf("%s is forbidden, connecting to the kubelet on port %d instead of the one in the node status", nodesPermission, adjusted.Port))
+		}
+	}
+
+	discoversPods := !c.DiscoverServices && !c.DiscoverCustomResources && !c.DiscoverNodes
+	podsPermission := Permission{Verb: "list", Resource: "pods"}
+	switch {
+	case c.PodsSource == config.PodsSourceAPIServer:
+		forbidden, all := forbiddenNamespaces(podsPermission, c.Namespaces, allowed)
+		switch {
+		case all && discoversPods:
+			return nil, nil, fmt.Errorf("%s is %w", podsPermission, ErrForbidden)
+		case len(forbidden) > 0 && discoversPods:
+			degraded = append(degraded, fmt.Sprintf("%s is forbidden in namespaces %s, their pods are not discovered", podsPermission, strings.Join(forbidden, ", ")))
+		}
+	default:
+		if p := (Permission{Verb: "get", Resource: "nodes", Subresource: "proxy"}); !allowed(p) {
+			degraded = append(degraded, fmt.Sprintf("%s is forbidden, the kubelet is only reached directly instead of through the API server proxy", p))
+		}
+		if c.APIServerFallback && discoversPods && !allowed(podsPermission) {
+			degraded = append(degraded, fmt.Sprintf("%s is forbidden, pods are not listed from the API server when the kubelet is not reachable", podsPermission))
+		}
+	}
+
+	if c.DiscoverServices {
+		servicesPermission := Permission{Verb: "list", Resource: "services"}
+		forbidden, all := forbiddenNamespaces(servicesPermission, c.Namespaces, allowed)
+		switch {
+		case all:
+			return nil, nil, fmt.Errorf("%s is %w", servicesPermission, ErrForbidden)
+		case len(forbidden) > 0:
+			adjusted.SkipServiceNamespaces = forbidden
+			degraded = append(degraded, fmt.Sprintf("%s is forbidden in namespaces %s, their services are not discovered", servicesPermission, strings.Join(forbidden, ", ")))
+		}
+	}
+
+	return &adjusted, degraded, nil
+}
+
+// forbiddenNamespaces returns the namespaces where the permission is not granted, and whether it is not granted in
+// any of the namespaces discovered. Namespaces are only reviewed one by one if it is not granted in all of them.
+func forbiddenNamespaces(p Permission, namespaces []string, allowed func(Permission) bool) ([]string, bool) {
+	if allowed(p) {
+		return nil, false
+	}
+
+	var forbidden []string
+	for _, ns := range namespaces {
+		nsPermission := p
+		nsPermission.Namespace = ns
+		if !allowed(nsPermission) {
+			forbidden = append(forbidden, ns)
+		}
+	}
+	return forbidden, len(forbidden) == len(namespaces)
+}
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeAccessReviews returns a client allowing every permission but the forbidden ones, which are formatted as
+// Permission.String does. Reviewing the permissions in failing fails.
+func fakeAccessReviews(forbidden []string, failing []string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		p := Permission{Verb: attributes.Verb, Resource: attributes.Resource, Subresource: attributes.Subresource, Namespace: attributes.Namespace}
+
+		for _, f := range failing {
+			if f == p.String() {
+				return true, nil, errors.New("review failed")
+			}
+		}
+
+		review.Status.Allowed = true
+		for _, f := range forbidden {
+			if f == p.String() {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func TestPreflight(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tests := []struct {
+		name          string
+		config        config.Config
+		forbidden     []string
+		failing       []string
+		want          func(c *config.Config)
+		wantDegraded  int
+		wantForbidden bool
+	}{
+		{
+			name:   "everything_permitted",
+			config: config.Config{NodeName: "node", DiscoverServices: true, DiscoverNodes: true},
+		},
+		{
+			name:         "node_port_lookup_forbidden",
+			config:       config.Config{NodeName: "node", Port: 0},
+			forbidden:    []string{"get nodes"},
+			want:         func(c *config.Config) { c.Port = config.DefaultPort },
+			wantDegraded: 1,
+		},
+		{
+			name:         "port_configured",
+			config:       config.Config{NodeName: "node", Port: 10250},
+			forbidden:    []string{"get nodes"},
+			wantDegraded: 0,
+		},
+		{
+			name:          "node_discovery_forbidden",
+			config:        config.Config{Port: 10250, DiscoverNodes: true},
+			forbidden:     []string{"list nodes"},
+			wantForbidden: true,
+		},
+		{
+			name:         "api_proxy_forbidden",
+			config:       config.Config{Port: 10250},
+			forbidden:    []string{"get nodes/proxy"},
+			wantDegraded: 1,
+		},
+		{
+			name:         "api_proxy_not_used",
+			config:       config.Config{Port: 10250, PodsSource: config.PodsSourceAPIServer},
+			forbidden:    []string{"get nodes/proxy"},
+			wantDegraded: 0,
+		},
+		{
+			name:         "api_server_fallback_forbidden",
+			config:       config.Config{Port: 10250, APIServerFallback: true},
+			forbidden:    []string{"list pods"},
+			wantDegraded: 1,
+		},
+		{
+			name:          "pods_forbidden_in_api_server",
+			config:        config.Config{Port: 10250, PodsSource: config.PodsSourceAPIServer},
+			forbidden:     []string{"list pods"},
+			wantForbidden: true,
+		},
+		{
+			name:         "pods_forbidden_in_some_namespaces",
+			config:       config.Config{Port: 10250, PodsSource: config.PodsSourceAPIServer, Namespaces: []string{"default", "secret"}},
+			forbidden:    []string{"list pods", `list pods in namespace "secret"`},
+			wantDegraded: 1,
+		},
+		{
+			name:         "pods_not_discovered",
+			config:       config.Config{Port: 10250, PodsSource: config.PodsSourceAPIServer, DiscoverServices: true},
+			forbidden:    []string{"list pods"},
+			wantDegraded: 0,
+		},
+		{
+			name:          "services_forbidden_in_all_namespaces",
+			config:        config.Config{Port: 10250, DiscoverServices: true},
+			forbidden:     []string{"list services"},
+			wantForbidden: true,
+		},
+		{
+			name:   "services_permitted_in_all_namespaces",
+			config: config.Config{Port: 10250, DiscoverServices: true, Namespaces: []string{"default", "secret"}},
+			// Namespaces are not reviewed one by one, which would skip them.
+			forbidden: []string{`list services in namespace "secret"`},
+		},
+		{
+			name:         "services_forbidden_in_some_namespaces",
+			config:       config.Config{Port: 10250, DiscoverServices: true, Namespaces: []string{"default", "secret"}},
+			forbidden:    []string{"list services", `list services in namespace "secret"`},
+			want:         func(c *config.Config) { c.SkipServiceNamespaces = []string{"secret"} },
+			wantDegraded: 1,
+		},
+		{
+			name:          "services_forbidden_in_every_namespace",
+			config:        config.Config{Port: 10250, DiscoverServices: true, Namespaces: []string{"secret"}},
+			forbidden:     []string{"list services", `list services in namespace "secret"`},
+			wantForbidden: true,
+		},
+		{
+			name:         "review_failing",
+			config:       config.Config{NodeName: "node", DiscoverServices: true},
+			failing:      []string{"get nodes", "list services"},
+			wantDegraded: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			client := fakeAccessReviews(tt.forbidden, tt.failing)
+			adjusted, degraded, err := Preflight(context.Background(), client, &c, logger)
+			assert.Equal(t, tt.config, c, "Config given is not modified")
+			if tt.wantForbidden {
+				assert.ErrorIs(t, err, ErrForbidden)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, degraded, tt.wantDegraded)
+
+			want := tt.config
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			assert.Equal(t, want, *adjusted)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// retryPolicy returns the policy used for API server requests, shared with the kubelet client.
+func retryPolicy(c *config.Config) retry.Policy {
+	return retry.Policy{
+		MaxRetries: c.Retries,
+		Backoff:    c.Backoff,
+		MaxBackoff: c.MaxBackoff,
+	}
+}
+
+// retryAPI runs the API server request following the retry policy.
+// Unauthorized and forbidden errors are never retried, while the delay the API server suggests is honored.
+// Besides network errors, only transient API errors are retried.
+func retryAPI(ctx context.Context, policy retry.Policy, request func() error) error {
+	return policy.Do(ctx, func() error {
+		err := request()
+		if err == nil {
+			return nil
+		}
+
+		if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+			return retry.Permanent(err)
+		}
+
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+			return retry.After(err, time.Duration(seconds)*time.Second)
+		}
+
+		if isTransientAPIError(err) {
+			return err
+		}
+
+		return retry.Permanent(err)
+	})
+}
+
+func isTransientAPIError(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		// Not returned by the API server, e.g. a connection error.
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsUnexpectedServerError(err)
+}
@@ -3,8 +3,11 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -35,45 +38,101 @@ type ServiceInfo struct {
 
 // ServiceDiscoverer defines what functionality service discovery client provides.
 type ServiceDiscoverer interface {
-	FindServices(namespaces []string) ([]ServiceInfo, error)
+	FindServices(ctx context.Context, namespaces []string) ([]ServiceInfo, error)
 }
 
 type serviceDiscoverer struct {
-	clientset   *kubernetes.Clientset
+	clientset   kubernetes.Interface
+	policy      retry.Policy
+	pageSize    int
+	workers     int
+	skip        map[string]bool
 	ClusterName string
 }
 
-func (sd *serviceDiscoverer) FindServices(namespaces []string) ([]ServiceInfo, error) {
-	allServices, err := sd.getServices(namespaces)
-	if err != nil {
+func (sd *serviceDiscoverer) FindServices(ctx context.Context, namespaces []string) ([]ServiceInfo, error) {
+	if len(sd.skip) > 0 {
+		namespaces = slices.DeleteFunc(slices.Clone(namespaces), func(ns string) bool { return sd.skip[ns] })
+		if len(namespaces) == 0 {
+			return nil, nil
+		}
+	}
+
+	allServices, err := sd.getServices(ctx, namespaces)
+	if err != nil && !IsPartialResult(err) {
 		return nil, err
 	}
-	return transformServices(sd.ClusterName, allServices), nil
+	return transformServices(sd.ClusterName, allServices), err
 }
 
-func (sd *serviceDiscoverer) getServices(namespaces []string) ([]corev1.Service, error) {
-	ctx := context.Background()
-	var allServices []corev1.Service
-
+// getServices lists the services of each namespace concurrently, with at most workers namespaces at a time.
+// Namespaces whose services cannot be listed, e.g. forbidden by RBAC, are reported in a PartialResultError
+// along with the services of the rest, unless none of them could be listed.
+func (sd *serviceDiscoverer) getServices(ctx context.Context, namespaces []string) ([]corev1.Service, error) {
 	// If no namespaces specified, get from all namespaces
 	if len(namespaces) == 0 {
-		serviceList, err := sd.clientset.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		services, err := sd.listServices(ctx, metav1.NamespaceAll)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list services: %w", err)
 		}
-		return serviceList.Items, nil
+		return services, nil
 	}
 
-	// Get services from specified namespaces
-	for _, ns := range namespaces {
-		serviceList, err := sd.clientset.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to list services in namespace %s: %w", ns, err)
+	results := make([][]corev1.Service, len(namespaces))
+	errs := make([]error, len(namespaces))
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < min(max(sd.workers, 1), len(namespaces)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = sd.listServices(ctx, namespaces[i])
+			}
+		}()
+	}
+	for i := range namespaces {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var allServices []corev1.Service
+	var failed []error
+	for i, ns := range namespaces {
+		if errs[i] != nil {
+			failed = append(failed, fmt.Errorf("failed to list services in namespace %s: %w", ns, errs[i]))
+			continue
 		}
-		allServices = append(allServices, serviceList.Items...)
+		allServices = append(allServices, results[i]...)
 	}
 
-	return allServices, nil
+	return allServices, partialResult(len(namespaces), failed)
+}
+
+// listServices lists the services of the namespace page by page, retrying each request.
+func (sd *serviceDiscoverer) listServices(ctx context.Context, namespace string) ([]corev1.Service, error) {
+	var services []corev1.Service
+
+	opts := metav1.ListOptions{Limit: int64(sd.pageSize)}
+	for {
+		var serviceList *corev1.ServiceList
+		err := retryAPI(ctx, sd.policy, func() error {
+			var err error
+			serviceList, err = sd.clientset.CoreV1().Services(namespace).List(ctx, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		services = append(services, serviceList.Items...)
+		if serviceList.Continue == "" {
+			return services, nil
+		}
+		opts.Continue = serviceList.Continue
+	}
 }
 
 func transformServices(clusterName string, services []corev1.Service) []ServiceInfo {
@@ -114,9 +173,19 @@ func transformServices(clusterName string, services []corev1.Service) []ServiceI
 }
 
 // NewServiceDiscoverer creates a new service discoverer using the provided clientset.
-func NewServiceDiscoverer(clientset *kubernetes.Clientset, config *config.Config) ServiceDiscoverer {
+// Services of the namespaces to skip are not listed.
+func NewServiceDiscoverer(clientset kubernetes.Interface, config *config.Config) ServiceDiscoverer {
+	skip := map[string]bool{}
+	for _, ns := range config.SkipServiceNamespaces {
+		skip[ns] = true
+	}
+
 	return &serviceDiscoverer{
 		clientset:   clientset,
+		policy:      retryPolicy(config),
+		pageSize:    config.ServicesPageSize,
+		workers:     config.ServicesWorkers,
+		skip:        skip,
 		ClusterName: config.ClusterName,
 	}
 }
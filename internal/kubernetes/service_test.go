@@ -1,25 +1,29 @@
 package kubernetes
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/newrelic/nri-discovery-kubernetes/internal/config"
+	"github.com/newrelic/nri-discovery-kubernetes/internal/retry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const (
 	testClusterName = "test-cluster"
 )
 
-// Note: Testing NewServiceDiscoverer and FindServices with fake clientset is challenging
-// due to type incompatibility between *fake.Clientset and *kubernetes.Clientset.
-// The production code uses concrete types instead of interfaces.
-// We focus on testing the core transformation logic which is well-isolated.
-
 func TestNewServiceDiscoverer(t *testing.T) {
 	// Verify the constructor exists and has correct signature
 	cfg := &config.Config{
@@ -32,6 +36,132 @@ func TestNewServiceDiscoverer(t *testing.T) {
 	// The actual service discovery logic is tested through transformServices
 }
 
+func TestServiceDiscoverer_FindServices(t *testing.T) {
+	service := createClusterIPService()
+
+	t.Run("retries_transient_errors", func(t *testing.T) {
+		client := fake.NewSimpleClientset(&service)
+		calls := 0
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			if calls == 1 {
+				return true, nil, apierrors.NewServiceUnavailable("unavailable")
+			}
+			return false, nil, nil
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, Retries: 2, Backoff: retry.BackoffExponential, MaxBackoff: time.Millisecond})
+
+		services, err := sd.FindServices(context.Background(), []string{"default"})
+		require.NoError(t, err)
+		require.Len(t, services, 1)
+		assert.Equal(t, "nginx-service", services[0].Name)
+		assert.Equal(t, 2, calls, "List is retried once")
+	})
+
+	t.Run("forbidden_not_retried", func(t *testing.T) {
+		client := fake.NewSimpleClientset(&service)
+		calls := 0
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "", errors.New("rbac"))
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, Retries: 3})
+
+		_, err := sd.FindServices(context.Background(), nil)
+		assert.True(t, apierrors.IsForbidden(err))
+		assert.Equal(t, 1, calls, "Forbidden errors are not retried")
+	})
+
+	t.Run("lists_pages", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		var limits []int64
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			opts := action.(k8stesting.ListActionImpl).ListOptions
+			limits = append(limits, opts.Limit)
+
+			page := &corev1.ServiceList{}
+			switch opts.Continue {
+			case "":
+				page.Items = []corev1.Service{createClusterIPService()}
+				page.Continue = "second"
+			case "second":
+				page.Items = []corev1.Service{createNodePortService()}
+			}
+			return true, page, nil
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, ServicesPageSize: 1, ServicesWorkers: 1})
+
+		services, err := sd.FindServices(context.Background(), []string{"default"})
+		require.NoError(t, err)
+		require.Len(t, services, 2)
+		assert.Equal(t, "nginx-service", services[0].Name)
+		assert.Equal(t, "nodeport-service", services[1].Name)
+		assert.Equal(t, []int64{1, 1}, limits, "Every page is requested with the page size")
+	})
+
+	t.Run("partial_result_when_namespace_forbidden", func(t *testing.T) {
+		var objects []runtime.Object
+		namespaces := []string{"ns-0", "ns-1", "forbidden", "ns-3", "ns-4"}
+		for _, ns := range namespaces {
+			svc := createClusterIPService()
+			svc.Namespace = ns
+			objects = append(objects, &svc)
+		}
+
+		client := fake.NewSimpleClientset(objects...)
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetNamespace() == "forbidden" {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "", errors.New("rbac"))
+			}
+			return false, nil, nil
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, ServicesWorkers: 2})
+
+		services, err := sd.FindServices(context.Background(), namespaces)
+		var partialErr *PartialResultError
+		require.ErrorAs(t, err, &partialErr)
+		require.Len(t, partialErr.Errs, 1)
+		assert.True(t, apierrors.IsForbidden(partialErr.Errs[0]))
+		require.Len(t, services, 4)
+		for i, ns := range []string{"ns-0", "ns-1", "ns-3", "ns-4"} {
+			assert.Equal(t, ns, services[i].Namespace, "Services keep the order of the namespaces")
+		}
+	})
+
+	t.Run("fails_when_every_namespace_fails", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "", errors.New("rbac"))
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, ServicesWorkers: 2})
+
+		services, err := sd.FindServices(context.Background(), []string{"a", "b"})
+		assert.True(t, apierrors.IsForbidden(err))
+		assert.False(t, IsPartialResult(err))
+		assert.Nil(t, services)
+	})
+
+	t.Run("skips_namespaces", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetNamespace() == "forbidden" {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "", errors.New("rbac"))
+			}
+			return false, nil, nil
+		})
+
+		sd := NewServiceDiscoverer(client, &config.Config{ClusterName: testClusterName, SkipServiceNamespaces: []string{"forbidden"}})
+
+		_, err := sd.FindServices(context.Background(), []string{"default", "forbidden"})
+		assert.NoError(t, err, "Skipped namespaces are not listed")
+	})
+}
+
 func TestTransformServices(t *testing.T) {
 	tests := []struct {
 		name         string
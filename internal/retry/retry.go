@@ -0,0 +1,147 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	BackoffLinear            = "linear"             // BackoffLinear waits one more second on each retry.
+	BackoffExponential       = "exponential"        // BackoffExponential doubles the wait on each retry.
+	BackoffExponentialJitter = "exponential-jitter" // BackoffExponentialJitter randomizes between half and the whole exponential wait.
+
+	defaultBase = time.Second
+	maxShift    = 30
+)
+
+// Policy defines how many times and how often failed operations are retried.
+type Policy struct {
+	// MaxRetries is the number of attempts made, counted as the pester client used to, so at least one is always made.
+	MaxRetries int
+	Backoff    string
+	// MaxBackoff caps the wait between retries, including the one requested by servers. Zero means no cap.
+	MaxBackoff time.Duration
+	// Base is the unit the backoff is computed from, one second if not set.
+	Base time.Duration
+}
+
+// IsValidBackoff checks if the backoff strategy is supported.
+func IsValidBackoff(backoff string) bool {
+	switch backoff {
+	case BackoffLinear, BackoffExponential, BackoffExponentialJitter:
+		return true
+	}
+	return false
+}
+
+// Delay returns the wait before the given retry, starting from zero.
+// As the pester client used to, the linear backoff retries the first time right away.
+func (p Policy) Delay(retry int) time.Duration {
+	base := p.Base
+	if base == 0 {
+		base = defaultBase
+	}
+
+	shift := retry
+	if shift > maxShift {
+		shift = maxShift
+	}
+
+	var delay time.Duration
+	switch p.Backoff {
+	case BackoffExponential:
+		delay = base << shift
+	case BackoffExponentialJitter:
+		delay = base << shift
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) // nolint: gosec // jitter does not need a secure source
+	default:
+		delay = base * time.Duration(retry)
+	}
+
+	return p.Cap(delay)
+}
+
+// Exhausted checks whether no attempt is left after the given one, starting from zero.
+func (p Policy) Exhausted(attempt int) bool {
+	return attempt+1 >= p.MaxRetries
+}
+
+// Cap limits the delay to MaxBackoff, if set.
+func (p Policy) Cap(delay time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// Do runs op until it succeeds, it returns an error marked as Permanent, the retries are exhausted or the context
+// is done. Errors marked with After are retried after the given delay instead of the backoff one.
+func (p Policy) Do(ctx context.Context, op func() error) error {
+	for retry := 0; ; retry++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		delay := p.Delay(retry)
+		var after *afterError
+		if errors.As(err, &after) {
+			err = after.err
+			delay = p.Cap(after.delay)
+		}
+
+		if p.Exhausted(retry) {
+			return err
+		}
+
+		if waitErr := Wait(ctx, delay); waitErr != nil {
+			return fmt.Errorf("waiting to retry after %v: %w", err, waitErr)
+		}
+	}
+}
+
+// Wait blocks for the given delay, returning early with the context error if it is done.
+func Wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error so it is not retried.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type afterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// After marks an error to be retried after the given delay, e.g. the one requested by a server through Retry-After.
+func After(err error, delay time.Duration) error {
+	return &afterError{err: err, delay: delay}
+}
@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTest = errors.New("test error")
+
+func TestPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []time.Duration
+	}{
+		{
+			name:   "linear",
+			policy: Policy{Backoff: BackoffLinear},
+			want:   []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:   "linear_is_the_default",
+			policy: Policy{},
+			want:   []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:   "exponential",
+			policy: Policy{Backoff: BackoffExponential},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "exponential_capped",
+			policy: Policy{Backoff: BackoffExponential, MaxBackoff: 3 * time.Second},
+			want:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:   "exponential_custom_base",
+			policy: Policy{Backoff: BackoffExponential, Base: time.Millisecond},
+			want:   []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for retry, want := range tt.want {
+				assert.Equal(t, want, tt.policy.Delay(retry), "retry %d", retry)
+			}
+		})
+	}
+}
+
+func TestPolicy_DelayExponentialJitter(t *testing.T) {
+	p := Policy{Backoff: BackoffExponentialJitter}
+
+	for retry := 0; retry < 5; retry++ {
+		exponential := time.Second << retry
+		for i := 0; i < 20; i++ {
+			delay := p.Delay(retry)
+			assert.GreaterOrEqual(t, delay, exponential/2)
+			assert.LessOrEqual(t, delay, exponential)
+		}
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	p := Policy{MaxRetries: 3, Backoff: BackoffExponential, Base: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "succeeds_first",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "succeeds_after_retries",
+			errs:      []error{errTest, errTest, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "retries_exhausted",
+			errs:      []error{errTest, errTest, errTest, errTest, errTest},
+			wantCalls: 3,
+			wantErr:   errTest,
+		},
+		{
+			name:      "permanent_not_retried",
+			errs:      []error{Permanent(errTest), nil},
+			wantCalls: 1,
+			wantErr:   errTest,
+		},
+		{
+			name:      "after_retried",
+			errs:      []error{After(errTest, time.Millisecond), nil},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.wantErr, err, "error is returned unwrapped")
+		})
+	}
+}
+
+func TestPolicy_DoHonorsAfterCappedByMaxBackoff(t *testing.T) {
+	p := Policy{MaxRetries: 2, MaxBackoff: 10 * time.Millisecond}
+
+	start := time.Now()
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return After(errTest, time.Hour)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPolicy_DoStopsWhenContextIsDone(t *testing.T) {
+	p := Policy{MaxRetries: 5, Backoff: BackoffExponential, Base: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	err := p.Do(ctx, func() error {
+		calls++
+		return errTest
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, calls)
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 )
 
 // Contains checks if given value is included in given slice.
@@ -22,3 +24,34 @@ func HomeDir() string {
 	}
 	return os.Getenv("USERPROFILE") // windows
 }
+
+// WriteFileAtomic writes data to a temporary file in the same directory and renames it to filename,
+// so readers never observe a partially written file.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	// Removing the temporary file is a no-op once it has been renamed.
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() // nolint: errcheck
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close() // nolint: errcheck
+		return fmt.Errorf("setting temporary file permissions: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("renaming temporary file: %w", err)
+	}
+
+	return nil
+}
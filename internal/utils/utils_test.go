@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_Contains_Return_True(t *testing.T) {
@@ -49,3 +52,34 @@ func Test_Contains_Return_False_IfEmptySet(t *testing.T) {
 	// then
 	assert.False(t, contains)
 }
+
+func Test_WriteFileAtomic_Replaces_Content(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file.json")
+	require.NoError(t, os.WriteFile(filename, []byte("old"), 0o600))
+
+	// when
+	err := WriteFileAtomic(filename, []byte("new"), 0o600)
+
+	// then
+	require.NoError(t, err)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func Test_WriteFileAtomic_Fails_IfDirectoryDoesNotExist(t *testing.T) {
+	// given
+	filename := filepath.Join(t.TempDir(), "missing", "file.json")
+
+	// when
+	err := WriteFileAtomic(filename, []byte("data"), 0o600)
+
+	// then
+	assert.Error(t, err)
+}